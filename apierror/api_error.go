@@ -0,0 +1,95 @@
+package apierror
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain of ErrorInfo details returned by auth service.
+const Domain = "auth.kotlang"
+
+// Reason codes sent in ErrorInfo so that clients can branch without parsing messages.
+const (
+	ReasonOtpResendCooldown     = "OTP_RESEND_COOLDOWN"
+	ReasonOtpDailyQuota         = "OTP_DAILY_QUOTA_EXCEEDED"
+	ReasonRateLimited           = "RATE_LIMITED"
+	ReasonWrongOtp              = "WRONG_OTP"
+	ReasonLockedOut             = "LOCKED_OUT"
+	ReasonUserBlocked           = "USER_BLOCKED"
+	ReasonUserSuspended         = "USER_SUSPENDED"
+	ReasonUserMarkedForDeletion = "USER_MARKED_FOR_DELETION"
+	ReasonRefreshTokenInvalid   = "REFRESH_TOKEN_INVALID"
+	ReasonRefreshTokenReused    = "REFRESH_TOKEN_REUSED"
+	ReasonSessionRevoked        = "SESSION_REVOKED"
+	// access token was invalidated by an admin action, client should refresh or login again.
+	ReasonTokenStale   = "TOKEN_STALE"
+	ReasonUserNotFound = "USER_NOT_FOUND"
+	ReasonInvalidToken = "INVALID_TOKEN"
+
+	ReasonTenantUnknown   = "TENANT_UNKNOWN"
+	ReasonTenantSuspended = "TENANT_SUSPENDED"
+
+	// tenant policy rejected the request.
+	ReasonIdentifierNotAllowed = "IDENTIFIER_NOT_ALLOWED"
+	ReasonRegistrationClosed   = "REGISTRATION_CLOSED"
+)
+
+// New returns a grpc status error with ErrorInfo details.
+// RetryInfo is added when retryAfter is positive.
+func New(code codes.Code, reason, message string, retryAfter time.Duration, metadata map[string]string) error {
+	st := status.New(code, message)
+
+	errorInfo := &errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   Domain,
+		Metadata: metadata,
+	}
+
+	var err error
+	if retryAfter > 0 {
+		st, err = st.WithDetails(errorInfo, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter.Round(time.Second))})
+	} else {
+		st, err = st.WithDetails(errorInfo)
+	}
+
+	if err != nil {
+		logger.Error("Failed adding error details", zap.String("reason", reason), zap.Error(err))
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
+
+// UserBlocked tells a blocked user why and, for suspensions, when they can retry.
+// expiresOn is 0 for blocks lasting until unblocked.
+func UserBlocked(blockReason string, expiresOn int64) error {
+	metadata := map[string]string{"blockReason": blockReason}
+	if expiresOn == 0 {
+		return New(codes.PermissionDenied, ReasonUserBlocked, "User is blocked", 0, metadata)
+	}
+
+	until := time.Unix(expiresOn, 0)
+	metadata["expiresOn"] = strconv.FormatInt(expiresOn, 10)
+	return New(codes.PermissionDenied, ReasonUserSuspended, "User is suspended until "+until.UTC().Format(time.RFC3339), time.Until(until), metadata)
+}
+
+// ReasonOf returns the ErrorInfo reason of an error created by New, empty otherwise.
+func ReasonOf(err error) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+
+	for _, detail := range st.Details() {
+		if errorInfo, ok := detail.(*errdetails.ErrorInfo); ok && errorInfo.Domain == Domain {
+			return errorInfo.Reason
+		}
+	}
+	return ""
+}
@@ -1,9 +1,75 @@
 package appconfig
 
-import "github.com/SaiNageswarS/go-api-boot/config"
+import (
+	"strings"
+
+	"github.com/SaiNageswarS/go-api-boot/config"
+)
 
 type AppConfig struct {
 	config.BootConfig `ini:",extends"`
 	MongoURI          string `ini:"mongo_uri"`
-	ProfileBucket     string `ini:"profile_bucket"`
+	// database holding data shared by all tenants like the tenant registry. Defaults to auth_control_plane.
+	ControlPlaneDb string `ini:"control_plane_db"`
+	// admins of this tenant manage the tenant registry. Super admins are users of this tenant.
+	PlatformTenant string `ini:"platform_tenant"`
+	ProfileBucket  string `ini:"profile_bucket"`
+	// address users reach the http port at, used in data export download links.
+	PublicBaseUrl string `ini:"public_base_url"`
+	// one of dev, twilio, native. See otp.ProvideOtpClientForMode.
+	OtpMode string `ini:"otp_mode"`
+	// resending otp within these minutes escalates to next delivery medium. Defaults to 5.
+	OtpEscalationMinutes int `ini:"otp_escalation_minutes"`
+
+	// proxies whose x-forwarded-for is used as the client ip for rate limits and audit logs.
+	// Clients connecting directly are identified by their own address. Format: 10.0.0.0/8,192.168.1.10/32
+	// Per ip rate limits are enforced only when set.
+	TrustedProxies string `ini:"trusted_proxies"`
+
+	// region used to parse phone numbers without country code.
+	DefaultPhoneRegion string `ini:"default_phone_region"`
+	// per tenant override of default_phone_region. Format: tenant1:KE,tenant2:US
+	TenantPhoneRegions string `ini:"tenant_phone_regions"`
+
+	// services allowed to call IntrospectToken. Secret of each is read from INTROSPECTION-SECRET-<client> env variable.
+	// Format: notification,social
+	IntrospectionClients string `ini:"introspection_clients"`
+
+	// accounts marked for deletion are hard deleted after these days. Defaults to 30.
+	DeletionGraceDays int `ini:"deletion_grace_days"`
+	// how often the deletion worker scans tenants. Defaults to 60.
+	DeletionIntervalMinutes int `ini:"deletion_interval_minutes"`
+	// deletion worker only logs and counts what it would delete.
+	DeletionDryRun bool `ini:"deletion_dry_run"`
+	// services which remove their data of deleted users and acknowledge user.deleted events.
+	// They authenticate with the same client secrets as introspection clients. Format: social,notification
+	DeletionSubscribers string `ini:"deletion_subscribers"`
+
+	// smtp password is read from SMTP-PASSWORD env variable.
+	SmtpHost        string `ini:"smtp_host"`
+	SmtpPort        int    `ini:"smtp_port"`
+	SmtpUsername    string `ini:"smtp_username"`
+	SmtpFromAddress string `ini:"smtp_from_address"`
+	SmtpFromName    string `ini:"smtp_from_name"`
+	// only for local smtp stand-ins which don't support STARTTLS.
+	SmtpAllowPlain bool `ini:"smtp_allow_plain"`
+}
+
+func (c *AppConfig) ControlPlaneDatabase() string {
+	if c.ControlPlaneDb == "" {
+		return "auth_control_plane"
+	}
+	return c.ControlPlaneDb
+}
+
+// PhoneRegion returns the region used to parse phone numbers of the tenant.
+func (c *AppConfig) PhoneRegion(tenant string) string {
+	for _, entry := range strings.Split(c.TenantPhoneRegions, ",") {
+		name, region, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if found && name == tenant {
+			return strings.TrimSpace(region)
+		}
+	}
+
+	return c.DefaultPhoneRegion
 }
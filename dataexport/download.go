@@ -0,0 +1,77 @@
+package dataexport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"go.uber.org/zap"
+)
+
+// DownloadHandler serves an export archive once. The link stops working after the first
+// successful download or when the export expires, whichever is earlier.
+func (e *Exporter) DownloadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantName := r.URL.Query().Get("tenant")
+		token := r.URL.Query().Get("token")
+		exportId, _, found := strings.Cut(token, ".")
+		if !tenant.IsValidName(tenantName) || !found || exportId == "" {
+			http.Error(w, "Invalid download link", http.StatusBadRequest)
+			return
+		}
+
+		export, err := db.ClaimDataExport(r.Context(), e.mongo, tenantName, exportId, hashToken(token), time.Now().Unix())
+		if err != nil {
+			logger.Error("Failed claiming data export", zap.String("exportId", exportId), zap.Error(err))
+			http.Error(w, "Failed getting export", http.StatusInternalServerError)
+			return
+		}
+		if export == nil {
+			http.Error(w, "Download link is expired or already used", http.StatusGone)
+			return
+		}
+
+		localPath, err := e.cloud.DownloadFile(r.Context(), e.bucket, export.Path)
+		if err != nil {
+			logger.Error("Failed downloading data export", zap.String("exportId", exportId), zap.Error(err))
+			// nothing was sent, the user can retry with the same link.
+			if err := db.UnclaimDataExport(context.Background(), e.mongo, tenantName, exportId); err != nil {
+				logger.Error("Failed releasing data export", zap.String("exportId", exportId), zap.Error(err))
+			}
+			http.Error(w, "Failed getting export", http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(localPath)
+
+		file, err := os.Open(localPath)
+		if err != nil {
+			logger.Error("Failed opening data export", zap.String("exportId", exportId), zap.Error(err))
+			http.Error(w, "Failed getting export", http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%s.zip"`, exportId))
+		w.Header().Set("Cache-Control", "no-store")
+		http.ServeContent(w, r, "", time.Unix(export.CreatedOn, 0), file)
+
+		db.SaveAuditLog(r.Context(), e.mongo, tenantName, db.AuditLogModel{
+			Action:  db.AuditDataExportDownloaded,
+			ActorId: export.UserId,
+			Target:  exportId,
+		})
+
+		if e.files != nil {
+			if _, err := e.files.DeleteByPrefix(r.Context(), e.bucket, export.Path, false); err != nil {
+				logger.Error("Failed deleting downloaded data export", zap.String("exportId", exportId), zap.Error(err))
+			}
+		}
+	}
+}
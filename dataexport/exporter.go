@@ -0,0 +1,198 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Kotlang/authGo/appconfig"
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/deletion"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/cloud"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// DownloadPath serves export archives on the http port: /exports/download?tenant=<tenant>&token=<token>
+	DownloadPath = "/exports/download"
+	// download links stop working after this long even if unused.
+	linkTtl = 15 * time.Minute
+	// latest audit events included in an export.
+	maxAuditEvents = 1000
+)
+
+// Exporter packages a user's personal data as a zip of json files in the profile bucket
+// and hands out single use links to download it.
+type Exporter struct {
+	mongo   odm.MongoClient
+	cloud   cloud.Cloud
+	files   deletion.ImageStore
+	bucket  string
+	baseUrl string
+}
+
+// files is used to remove downloaded archives from the bucket, downloaded archives are kept if nil.
+// Expired archives are removed by the deletion worker.
+func ProvideExporter(mongo odm.MongoClient, ccfg *appconfig.AppConfig, cloudFns cloud.Cloud, files deletion.ImageStore) (*Exporter, error) {
+	// download links are sent to users, a relative link would not work.
+	if strings.TrimSpace(ccfg.PublicBaseUrl) == "" {
+		return nil, errors.New("public_base_url is not set")
+	}
+
+	return &Exporter{
+		mongo:   mongo,
+		cloud:   cloudFns,
+		files:   files,
+		bucket:  ccfg.ProfileBucket,
+		baseUrl: strings.TrimSuffix(strings.TrimSpace(ccfg.PublicBaseUrl), "/"),
+	}, nil
+}
+
+// session without its refresh token hashes.
+type sessionRecord struct {
+	SessionId       string        `json:"sessionId"`
+	Device          db.DeviceInfo `json:"device"`
+	CreatedOn       int64         `json:"createdOn"`
+	LastRefreshedOn int64         `json:"lastRefreshedOn"`
+	ExpiresOn       int64         `json:"expiresOn"`
+	RevokedOn       int64         `json:"revokedOn"`
+	RevokeReason    string        `json:"revokeReason"`
+}
+
+// Create gathers the user's data, uploads the archive and returns the export with its download link.
+func (e *Exporter) Create(ctx context.Context, tenant, userId string) (*db.DataExportModel, string, error) {
+	if e.bucket == "" {
+		return nil, "", errors.New("profile_bucket is not set")
+	}
+
+	archive, err := e.buildArchive(ctx, tenant, userId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	exportId := uuid.New().String()
+	// under the user's folder so that the archive goes with the account when it is purged.
+	path := fmt.Sprintf("%s/%s/exports/%s.zip", tenant, userId, exportId)
+	if _, err := e.cloud.UploadBuffer(ctx, e.bucket, path, archive); err != nil {
+		return nil, "", err
+	}
+
+	token, err := newDownloadToken(exportId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	export := db.DataExportModel{
+		ExportId:  exportId,
+		UserId:    userId,
+		Path:      path,
+		TokenHash: hashToken(token),
+		ExpiresOn: time.Now().Add(linkTtl).Unix(),
+	}
+	if _, err := async.Await(odm.CollectionOf[db.DataExportModel](e.mongo, tenant).Save(ctx, export)); err != nil {
+		return nil, "", err
+	}
+
+	link := fmt.Sprintf("%s%s?tenant=%s&token=%s", e.baseUrl, DownloadPath, url.QueryEscape(tenant), url.QueryEscape(token))
+	return &export, link, nil
+}
+
+func (e *Exporter) buildArchive(ctx context.Context, tenant, userId string) ([]byte, error) {
+	login, err := async.Await(odm.CollectionOf[db.LoginModel](e.mongo, tenant).FindOneByID(ctx, userId))
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := async.Await(odm.CollectionOf[db.ProfileModel](e.mongo, tenant).FindOneByID(ctx, userId))
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	leads := []db.LeadModel{}
+	if login.Phone != "" {
+		if leads, err = async.Await(db.FindLeadsByPhone(ctx, e.mongo, tenant, login.Phone)); err != nil {
+			return nil, err
+		}
+	}
+
+	userSessions, err := async.Await(odm.CollectionOf[db.SessionModel](e.mongo, tenant).Find(ctx,
+		bson.M{"userId": userId}, bson.D{{Key: "createdOn", Value: -1}}, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	sessions := []sessionRecord{}
+	for _, userSession := range userSessions {
+		sessions = append(sessions, sessionRecord{
+			SessionId:       userSession.SessionId,
+			Device:          userSession.Device,
+			CreatedOn:       userSession.CreatedOn,
+			LastRefreshedOn: userSession.LastRefreshedOn,
+			ExpiresOn:       userSession.ExpiresOn,
+			RevokedOn:       userSession.RevokedOn,
+			RevokeReason:    userSession.RevokeReason,
+		})
+	}
+
+	auditEvents, err := async.Await(db.FindAuditLogsOfUser(ctx, e.mongo, tenant, userId, maxAuditEvents))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"login.json", login},
+		{"profile.json", profile},
+		{"leads.json", leads},
+		{"sessions.json", sessions},
+		{"audit_events.json", auditEvents},
+	}
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.content, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newDownloadToken(exportId string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return exportId + "." + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+)
+
+// audited actions.
+const (
+	AuditTestAccountOtpRequested = "test_account.otp_requested"
+	AuditTestAccountVerified     = "test_account.verified"
+	AuditTestAccountSaved        = "test_account.saved"
+	AuditTestAccountDeleted      = "test_account.deleted"
+	AuditRefreshTokenReused      = "session.refresh_token_reused"
+	AuditSessionRevoked          = "session.revoked"
+	AuditAllSessionsRevoked      = "session.all_revoked"
+	AuditTenantCreated           = "tenant.created"
+	AuditTenantStatusChanged     = "tenant.status_changed"
+	AuditTenantPolicyChanged     = "tenant.policy_changed"
+	AuditRoleSaved               = "role.saved"
+	AuditRoleDeleted             = "role.deleted"
+	AuditRoleAssigned            = "role.assigned"
+	AuditRoleUnassigned          = "role.unassigned"
+	AuditCrossTenantCall         = "cross_tenant.call"
+	AuditSuperAdminGranted       = "super_admin.granted"
+	AuditSuperAdminRevoked       = "super_admin.revoked"
+	AuditUserBlocked             = "user.blocked"
+	AuditUserSuspended           = "user.suspended"
+	AuditUserUnblocked           = "user.unblocked"
+	AuditUserPurged              = "user.purged"
+	AuditDeletionAcknowledged    = "user.deletion_acknowledged"
+	AuditDataExportCreated       = "data_export.created"
+	AuditDataExportDownloaded    = "data_export.downloaded"
+)
+
+// AuditLogModel records a security relevant action.
+type AuditLogModel struct {
+	AuditId string `bson:"_id"`
+	Action  string `bson:"action"`
+	// user performing the action, empty for unauthenticated calls.
+	ActorId string `bson:"actorId"`
+	// set when a super admin of another tenant performed the action.
+	ActorTenant string            `bson:"actorTenant,omitempty"`
+	CrossTenant bool              `bson:"crossTenant,omitempty"`
+	Target      string            `bson:"target"`
+	Details     map[string]string `bson:"details"`
+	CreatedOn   int64             `bson:"createdOn,omitempty"`
+}
+
+func (m AuditLogModel) Id() string {
+	if m.AuditId == "" {
+		m.AuditId = uuid.New().String()
+	}
+	return m.AuditId
+}
+
+func (m AuditLogModel) CollectionName() string { return "audit_logs" }
+
+// SaveAuditLog stores the entry in tenant's audit log.
+// Failures are logged and do not fail the audited action.
+func SaveAuditLog(ctx context.Context, mongo odm.MongoClient, tenant string, entry AuditLogModel) {
+	entry.AuditId = entry.Id()
+	_, err := async.Await(odm.CollectionOf[AuditLogModel](mongo, tenant).Save(ctx, entry))
+	if err != nil {
+		logger.Error("Failed saving audit log", zap.String("action", entry.Action), zap.String("target", entry.Target), zap.Error(err))
+	}
+}
+
+// FindAuditLogsOfUser finds the latest entries where the user acted or was acted upon.
+func FindAuditLogsOfUser(ctx context.Context, mongo odm.MongoClient, tenant, userId string, limit int64) <-chan async.Result[[]AuditLogModel] {
+	filter := bson.M{"$or": bson.A{bson.M{"actorId": userId}, bson.M{"target": userId}}}
+	return odm.CollectionOf[AuditLogModel](mongo, tenant).Find(ctx, filter, bson.D{{Key: "createdOn", Value: -1}}, limit, 0)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DataExportModel is an archive of a user's personal data waiting to be downloaded.
+// The download link works once, until the export expires.
+type DataExportModel struct {
+	ExportId string `bson:"_id"`
+	UserId   string `bson:"userId"`
+	// path of the archive in the profile bucket.
+	Path      string `bson:"path"`
+	TokenHash string `bson:"tokenHash"`
+	ExpiresOn int64  `bson:"expiresOn"`
+	// 0 until the archive is downloaded.
+	DownloadedOn int64 `bson:"downloadedOn"`
+	CreatedOn    int64 `bson:"createdOn,omitempty"`
+}
+
+func (m DataExportModel) Id() string { return m.ExportId }
+
+func (m DataExportModel) CollectionName() string { return "data_exports" }
+
+// ClaimDataExport atomically marks the export downloaded if the token is its own and the export is neither used nor expired.
+// Returns nil if the link is not valid.
+func ClaimDataExport(ctx context.Context, client odm.MongoClient, tenant, exportId, tokenHash string, now int64) (*DataExportModel, error) {
+	filter := bson.M{
+		"_id":          exportId,
+		"tokenHash":    tokenHash,
+		"downloadedOn": 0,
+		"expiresOn":    bson.M{"$gt": now},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	export := &DataExportModel{}
+	err := client.Database(tenant).Collection(export.CollectionName()).
+		FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"downloadedOn": now}}, opts).
+		Decode(export)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// UnclaimDataExport makes the link usable again when the download failed before any data was sent.
+func UnclaimDataExport(ctx context.Context, client odm.MongoClient, tenant, exportId string) error {
+	_, err := client.Database(tenant).Collection(DataExportModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": exportId}, bson.M{"$set": bson.M{"downloadedOn": 0}})
+	return err
+}
+
+// FindExpiredDataExports finds exports which expired before the unix time, oldest first.
+func FindExpiredDataExports(ctx context.Context, client odm.MongoClient, tenant string, before, limit int64) <-chan async.Result[[]DataExportModel] {
+	return odm.CollectionOf[DataExportModel](client, tenant).Find(ctx,
+		bson.M{"expiresOn": bson.M{"$lt": before}},
+		bson.D{{Key: "expiresOn", Value: 1}}, limit, 0)
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// acknowledgement status of a downstream service.
+const (
+	DeletionAckPending = "pending"
+	DeletionAckDone    = "done"
+	DeletionAckFailed  = "failed"
+)
+
+// what deleted the user.
+const (
+	DeletionSourceAdmin       = "admin"
+	DeletionSourceGracePeriod = "grace_period"
+)
+
+// DeletionAck is the state of a deleted user's data in a downstream service.
+type DeletionAck struct {
+	Status string `bson:"status"`
+	// reported by the service, e.g. what was removed or why it failed.
+	Details        string `bson:"details"`
+	AcknowledgedOn int64  `bson:"acknowledgedOn"`
+}
+
+// DeletionEventModel is a user.deleted event waiting to be published and acknowledged.
+// Saved before the user is deleted so that the event survives restarts until it is published.
+type DeletionEventModel struct {
+	EventId string `bson:"_id"`
+	UserId  string `bson:"userId"`
+	Source  string `bson:"source"`
+	// downstream service -> its acknowledgement.
+	Acks map[string]DeletionAck `bson:"acks"`
+	// 0 until the event is handed to the event sink.
+	PublishedOn     int64  `bson:"publishedOn"`
+	PublishAttempts int    `bson:"publishAttempts"`
+	LastError       string `bson:"lastError"`
+	// set when deleting the user failed, cancelled events are never published.
+	CancelledOn  int64  `bson:"cancelledOn"`
+	CancelReason string `bson:"cancelReason"`
+	CreatedOn    int64  `bson:"createdOn,omitempty"`
+}
+
+// matches events saved before cancellation was tracked too.
+var notCancelled = bson.M{"$not": bson.M{"$gt": 0}}
+
+func (m DeletionEventModel) Id() string {
+	if m.EventId == "" {
+		m.EventId = uuid.New().String()
+	}
+	return m.EventId
+}
+
+func (m DeletionEventModel) CollectionName() string { return "deletion_events" }
+
+// IsComplete reports if every downstream service acknowledged removing the user's data.
+func (m DeletionEventModel) IsComplete() bool {
+	for _, ack := range m.Acks {
+		if ack.Status != DeletionAckDone {
+			return false
+		}
+	}
+	return m.PublishedOn > 0 && m.CancelledOn == 0
+}
+
+func FindUnpublishedDeletionEvents(ctx context.Context, mongo odm.MongoClient, tenant string, limit int64) <-chan async.Result[[]DeletionEventModel] {
+	return odm.CollectionOf[DeletionEventModel](mongo, tenant).Find(ctx,
+		bson.M{"publishedOn": 0, "cancelledOn": notCancelled},
+		bson.D{{Key: "createdOn", Value: 1}}, limit, 0)
+}
+
+// FindLatestDeletionEvent finds the last deletion event of the user which was not cancelled.
+func FindLatestDeletionEvent(ctx context.Context, mongo odm.MongoClient, tenant, userId string) (*DeletionEventModel, error) {
+	events, err := async.Await(odm.CollectionOf[DeletionEventModel](mongo, tenant).Find(ctx,
+		bson.M{"userId": userId, "cancelledOn": notCancelled},
+		bson.D{{Key: "createdOn", Value: -1}}, 1, 0))
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return &events[0], nil
+}
+
+func MarkDeletionEventPublished(ctx context.Context, mongo odm.MongoClient, tenant, eventId string, publishedOn int64) error {
+	_, err := mongo.Database(tenant).Collection(DeletionEventModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": eventId}, bson.M{
+			"$set": bson.M{"publishedOn": publishedOn, "lastError": ""},
+			"$inc": bson.M{"publishAttempts": 1},
+		})
+	return err
+}
+
+func RecordDeletionPublishFailure(ctx context.Context, mongo odm.MongoClient, tenant, eventId, lastError string) error {
+	_, err := mongo.Database(tenant).Collection(DeletionEventModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": eventId}, bson.M{
+			"$set": bson.M{"lastError": lastError},
+			"$inc": bson.M{"publishAttempts": 1},
+		})
+	return err
+}
+
+// CancelDeletionEvent cancels the event unless it is already published.
+func CancelDeletionEvent(ctx context.Context, mongo odm.MongoClient, tenant, eventId, reason string, cancelledOn int64) error {
+	_, err := mongo.Database(tenant).Collection(DeletionEventModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": eventId, "publishedOn": 0}, bson.M{
+			"$set": bson.M{"cancelledOn": cancelledOn, "cancelReason": reason},
+		})
+	return err
+}
+
+// AcknowledgeDeletionEvent records the service's acknowledgement.
+// Returns false if there is no such event or the service is not expected to acknowledge it.
+func AcknowledgeDeletionEvent(ctx context.Context, mongo odm.MongoClient, tenant, eventId, service string, ack DeletionAck) (bool, error) {
+	res, err := mongo.Database(tenant).Collection(DeletionEventModel{}.CollectionName()).
+		UpdateOne(ctx,
+			bson.M{"_id": eventId, "acks." + service: bson.M{"$exists": true}},
+			bson.M{"$set": bson.M{"acks." + service: ack}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// DeletionTombstoneModel records that an account was hard deleted after its grace period.
+// It holds no personal data, identifiers are kept only as keyed hashes.
+type DeletionTombstoneModel struct {
+	UserId string `bson:"_id"`
+	// hmac-sha256 of the email and phone the account had.
+	IdentifierHashes []string `bson:"identifierHashes"`
+	// when the user asked for deletion.
+	RequestedOn   int64 `bson:"requestedOn"`
+	LeadsDeleted  int64 `bson:"leadsDeleted"`
+	ImagesDeleted int   `bson:"imagesDeleted"`
+	// time of hard deletion.
+	CreatedOn int64 `bson:"createdOn,omitempty"`
+}
+
+func (m DeletionTombstoneModel) Id() string { return m.UserId }
+
+func (m DeletionTombstoneModel) CollectionName() string { return "deletion_tombstones" }
+
+// HashIdentifier hashes an email or phone for tombstones.
+// The hash is keyed so that phone numbers cannot be recovered by hashing every number.
+func HashIdentifier(key []byte, identifier string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(identifier))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,17 @@
+package db
+
+// EmailTemplateModel is a tenant specific email template in a given language.
+// Subject and TextBody are text templates, HtmlBody is an html template.
+type EmailTemplateModel struct {
+	Language string `bson:"language"`
+	Name     string `bson:"name"`
+	Subject  string `bson:"subject"`
+	TextBody string `bson:"textBody"`
+	HtmlBody string `bson:"htmlBody"`
+}
+
+func (m EmailTemplateModel) Id() string {
+	return m.Language + "/" + m.Name
+}
+
+func (m EmailTemplateModel) CollectionName() string { return "email_templates" }
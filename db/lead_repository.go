@@ -4,6 +4,7 @@ import (
 	"context"
 
 	authPb "github.com/Kotlang/authGo/generated/auth"
+	"github.com/Kotlang/authGo/phonenumber"
 	"github.com/SaiNageswarS/go-api-boot/async"
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
@@ -50,6 +51,33 @@ func FindLeadsByIds(ctx context.Context, mongo odm.MongoClient, tenant string, i
 	return odm.CollectionOf[LeadModel](mongo, tenant).Find(ctx, filter, nil, 0, 0)
 }
 
+// FindLeadsByPhone finds leads captured with any form of the phone number.
+func FindLeadsByPhone(ctx context.Context, mongo odm.MongoClient, tenant, phone string) <-chan async.Result[[]LeadModel] {
+	return odm.CollectionOf[LeadModel](mongo, tenant).Find(ctx, bson.M{"phoneNumber": bson.M{"$in": phonenumber.Variants(phone)}}, nil, 0, 0)
+}
+
+// CountLeadsByPhone counts leads captured with any form of the phone number.
+func CountLeadsByPhone(ctx context.Context, mongo odm.MongoClient, tenant, phone string) (int64, error) {
+	if phone == "" {
+		return 0, nil
+	}
+	return mongo.Database(tenant).Collection(LeadModel{}.CollectionName()).
+		CountDocuments(ctx, bson.M{"phoneNumber": bson.M{"$in": phonenumber.Variants(phone)}})
+}
+
+// DeleteLeadsByPhone deletes leads captured with any form of the phone number and returns the number deleted.
+func DeleteLeadsByPhone(ctx context.Context, mongo odm.MongoClient, tenant, phone string) (int64, error) {
+	if phone == "" {
+		return 0, nil
+	}
+	res, err := mongo.Database(tenant).Collection(LeadModel{}.CollectionName()).
+		DeleteMany(ctx, bson.M{"phoneNumber": bson.M{"$in": phonenumber.Variants(phone)}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
 func GetLeads(ctx context.Context, mongo odm.MongoClient, tenant string, leadFilters *authPb.LeadFilters, PageSize, PageNumber int64) (leads []LeadModel, totalCount int) {
 
 	// get the filter
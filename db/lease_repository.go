@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LeaseModel elects a single replica to run a background job. Stored in the control plane database.
+type LeaseModel struct {
+	Name string `bson:"_id"`
+	// instance holding the lease.
+	Holder     string `bson:"holder"`
+	AcquiredOn int64  `bson:"acquiredOn"`
+	ExpiresOn  int64  `bson:"expiresOn"`
+}
+
+func (m LeaseModel) Id() string { return m.Name }
+
+func (m LeaseModel) CollectionName() string { return "leases" }
+
+// AcquireLease takes or renews the lease for holder if it is free or expired.
+// Returns false if another holder has it.
+func AcquireLease(ctx context.Context, client odm.MongoClient, database, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"holder": holder},
+			bson.M{"expiresOn": bson.M{"$lt": now.Unix()}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"holder": holder, "acquiredOn": now.Unix(), "expiresOn": now.Add(ttl).Unix()},
+	}
+
+	_, err := client.Database(database).Collection(LeaseModel{}.CollectionName()).
+		UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	// upsert collides with the lease of another holder on _id.
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReleaseLease frees the lease if holder has it, so that another replica can take over without waiting for expiry.
+func ReleaseLease(ctx context.Context, client odm.MongoClient, database, name, holder string) error {
+	_, err := client.Database(database).Collection(LeaseModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": name, "holder": holder}, bson.M{"$set": bson.M{"expiresOn": 0}})
+	return err
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LockoutModel tracks failed otp verifications of an email or phone.
+// Level is the number of lockouts so far and decides the next lockout duration.
+type LockoutModel struct {
+	Identifier     string    `bson:"_id"`
+	FailedAttempts int       `bson:"failedAttempts"`
+	Level          int       `bson:"level"`
+	LockedUntil    int64     `bson:"lockedUntil"`
+	ExpireAt       time.Time `bson:"expireAt"`
+}
+
+func (m LockoutModel) Id() string { return m.Identifier }
+
+func (m LockoutModel) CollectionName() string { return "lockouts" }
+
+// IncrementFailedAttempts atomically records a failed attempt and returns the updated lockout.
+func IncrementFailedAttempts(ctx context.Context, mongo odm.MongoClient, tenant, identifier string, expireAt time.Time) (*LockoutModel, error) {
+	update := bson.M{
+		"$inc": bson.M{"failedAttempts": 1},
+		"$set": bson.M{"expireAt": expireAt},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	lockout := &LockoutModel{}
+	err := mongo.Database(tenant).Collection(lockout.CollectionName()).
+		FindOneAndUpdate(ctx, bson.M{"_id": identifier}, update, opts).
+		Decode(lockout)
+	return lockout, err
+}
+
+// Lock locks the identifier if failed attempts have reached the threshold.
+// Returns false if another request has already locked it.
+func Lock(ctx context.Context, mongo odm.MongoClient, tenant, identifier string, threshold int, lockedUntil int64, expireAt time.Time) (bool, error) {
+	filter := bson.M{
+		"_id":            identifier,
+		"failedAttempts": bson.M{"$gte": threshold},
+	}
+	update := bson.M{
+		"$inc": bson.M{"level": 1},
+		"$set": bson.M{"failedAttempts": 0, "lockedUntil": lockedUntil, "expireAt": expireAt},
+	}
+
+	res, err := mongo.Database(tenant).Collection(LockoutModel{}.CollectionName()).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// ResetFailedAttempts clears failed attempts after a successful verification.
+// Level is retained so that repeated lockouts keep escalating until the record expires.
+func ResetFailedAttempts(ctx context.Context, mongo odm.MongoClient, tenant, identifier string) error {
+	_, err := mongo.Database(tenant).Collection(LockoutModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": identifier}, bson.M{"$set": bson.M{"failedAttempts": 0}})
+	return err
+}
@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"time"
 
+	"github.com/Kotlang/authGo/phonenumber"
 	"github.com/SaiNageswarS/go-api-boot/async"
 	"github.com/SaiNageswarS/go-api-boot/odm"
 	"github.com/google/uuid"
@@ -13,14 +15,44 @@ type LoginModel struct {
 	UserId               string       `bson:"_id"`
 	Email                string       `bson:"email"`
 	Phone                string       `bson:"phone"`
-	Otp                  string       `bson:"otp"`
 	UserType             string       `bson:"userType"`
 	LastOtpSentTime      int64        `bson:"lastOtpSentTime"`
+	LastOtpDelivery      string       `bson:"lastOtpDelivery"`
 	OtpAuthenticatedTime int64        `bson:"otpAuthenticatedTime"`
 	CreatedOn            int64        `bson:"createdOn,omitempty"`
 	DeletionInfo         DeletionInfo `bson:"deletionInfo" json:"deletionInfo"`
 	IsBlocked            bool         `bson:"isBlocked" json:"isBlocked"`
+	BlockInfo            BlockInfo    `bson:"blockInfo" json:"blockInfo"`
 	LastActive           int64        `bson:"lastActive" json:"lastActive"`
+	// access tokens carry the epoch they were issued in, bumping it invalidates them.
+	TokenEpoch int64 `bson:"tokenEpoch" json:"tokenEpoch"`
+	// names of RoleModel granting the user permissions.
+	Roles []string `bson:"roles" json:"roles"`
+	// created for an identifier reserved as a test account.
+	IsTestAccount bool `bson:"isTestAccount" json:"isTestAccount"`
+	// set when the deletion worker starts purging the account, it can no longer be restored.
+	// Never written as zero so that saving a stale login does not clear it.
+	PurgeClaimedOn int64 `bson:"purgeClaimedOn,omitempty" json:"purgeClaimedOn"`
+}
+
+// BlockInfo explains why a user is blocked.
+type BlockInfo struct {
+	Reason string `bson:"reason" json:"reason"`
+	// admin who blocked the user.
+	BlockedBy string `bson:"blockedBy" json:"blockedBy"`
+	BlockedOn int64  `bson:"blockedOn" json:"blockedOn"`
+	// suspensions lift at this time, 0 for blocks lasting until unblocked.
+	ExpiresOn int64 `bson:"expiresOn" json:"expiresOn"`
+}
+
+// IsBlockedAt reports if the user is blocked at the unix time, expired suspensions don't count.
+func (m LoginModel) IsBlockedAt(now int64) bool {
+	return m.IsBlocked && (m.BlockInfo.ExpiresOn == 0 || now < m.BlockInfo.ExpiresOn)
+}
+
+// IsDeleting reports if the user asked for deletion or is being purged.
+func (m LoginModel) IsDeleting() bool {
+	return m.DeletionInfo.MarkedForDeletion || m.PurgeClaimedOn != 0
 }
 
 func (m LoginModel) Id() string {
@@ -32,6 +64,7 @@ func (m LoginModel) Id() string {
 
 func (m LoginModel) CollectionName() string { return "login" }
 
+// Phone should be normalized to E.164. Records still holding the national number are matched too.
 func FindOneByPhoneOrEmail(ctx context.Context, mongo odm.MongoClient, tenant, phone, email string) chan *LoginModel {
 	ch := make(chan *LoginModel)
 
@@ -39,7 +72,7 @@ func FindOneByPhoneOrEmail(ctx context.Context, mongo odm.MongoClient, tenant, p
 		filter := bson.M{}
 
 		if len(phone) > 0 {
-			filter["phone"] = phone
+			filter["phone"] = bson.M{"$in": phonenumber.Variants(phone)}
 		} else {
 			filter["email"] = email
 		}
@@ -54,15 +87,107 @@ func FindOneByPhoneOrEmail(ctx context.Context, mongo odm.MongoClient, tenant, p
 	return ch
 }
 
+// FindLoginByIdentifier finds login whose id is the email or phone used at signup.
+// Ids of users who signed up before phone normalization are the national number.
+func FindLoginByIdentifier(ctx context.Context, mongo odm.MongoClient, tenant, emailOrPhone string) <-chan async.Result[*LoginModel] {
+	return odm.CollectionOf[LoginModel](mongo, tenant).FindOne(ctx, bson.M{"_id": bson.M{"$in": phonenumber.Variants(emailOrPhone)}})
+}
+
+// FindLoginsOfIdentifier finds logins whose id, email or phone is the email or phone.
+func FindLoginsOfIdentifier(ctx context.Context, mongo odm.MongoClient, tenant, emailOrPhone string) <-chan async.Result[[]LoginModel] {
+	variants := phonenumber.Variants(emailOrPhone)
+	filter := bson.M{"$or": bson.A{
+		bson.M{"_id": bson.M{"$in": variants}},
+		bson.M{"phone": bson.M{"$in": variants}},
+		bson.M{"email": emailOrPhone},
+	}}
+	return odm.CollectionOf[LoginModel](mongo, tenant).Find(ctx, filter, nil, 0, 0)
+}
+
 func FindLoginsByIds(ctx context.Context, mongo odm.MongoClient, tenant string, ids []string) <-chan async.Result[[]LoginModel] {
 	return odm.CollectionOf[LoginModel](mongo, tenant).Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, nil, int64(len(ids)), 0)
 }
 
-func IsAdmin(mongo odm.MongoClient, tenant, id string) bool {
-	loginInfo, err := async.Await(odm.CollectionOf[LoginModel](mongo, tenant).FindOneByID(context.Background(), id))
+// matches logins marked for deletion before the unix time and logins whose purge did not finish.
+func pendingDeletionFilter(before int64) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{
+			"deletionInfo.markedForDeletion": true,
+			"deletionInfo.deletionTime":      bson.M{"$lt": before},
+		},
+		bson.M{"purgeClaimedOn": bson.M{"$gt": 0}},
+	}}
+}
+
+// FindLoginsPendingDeletion finds users who asked for deletion before the unix time.
+func FindLoginsPendingDeletion(ctx context.Context, mongo odm.MongoClient, tenant string, before, limit int64) <-chan async.Result[[]LoginModel] {
+	return odm.CollectionOf[LoginModel](mongo, tenant).Find(ctx, pendingDeletionFilter(before), bson.D{{Key: "deletionInfo.deletionTime", Value: 1}}, limit, 0)
+}
+
+// ClaimLoginForPurge marks the login as being purged if it is still pending deletion.
+// Returns false if the user restored the account meanwhile.
+func ClaimLoginForPurge(ctx context.Context, mongo odm.MongoClient, tenant, userId string, before int64) (bool, error) {
+	filter := pendingDeletionFilter(before)
+	filter["_id"] = userId
+
+	res, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		UpdateOne(ctx, filter, bson.M{"$set": bson.M{"purgeClaimedOn": time.Now().Unix()}})
 	if err != nil {
-		return false
+		return false, err
 	}
+	return res.MatchedCount == 1, nil
+}
+
+// DeletePurgedLogin deletes the login once the rest of the account is purged.
+func DeletePurgedLogin(ctx context.Context, mongo odm.MongoClient, tenant, userId string) error {
+	_, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		DeleteOne(ctx, bson.M{"_id": userId, "purgeClaimedOn": bson.M{"$gt": 0}})
+	return err
+}
+
+// RestoreLogin clears the deletion request unless the purge of the account has started.
+// Returns false if the user does not exist or is being purged.
+func RestoreLogin(ctx context.Context, mongo odm.MongoClient, tenant, userId string) (bool, error) {
+	filter := bson.M{"_id": userId, "purgeClaimedOn": bson.M{"$not": bson.M{"$gt": 0}}}
+
+	res, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		UpdateOne(ctx, filter, bson.M{"$set": bson.M{"deletionInfo": DeletionInfo{}}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// BlockLogin blocks the user with the given info. Returns false if the user does not exist.
+func BlockLogin(ctx context.Context, mongo odm.MongoClient, tenant, userId string, info BlockInfo) (bool, error) {
+	res, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$set": bson.M{"isBlocked": true, "blockInfo": info}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// UnblockLogin lifts a block or suspension. Returns false if the user does not exist.
+func UnblockLogin(ctx context.Context, mongo odm.MongoClient, tenant, userId string) (bool, error) {
+	res, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$set": bson.M{"isBlocked": false, "blockInfo": BlockInfo{}}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// BumpTokenEpoch atomically invalidates all access tokens issued to the user.
+func BumpTokenEpoch(ctx context.Context, mongo odm.MongoClient, tenant, userId string) error {
+	_, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$inc": bson.M{"tokenEpoch": 1}})
+	return err
+}
 
-	return loginInfo.UserType == "admin"
+// UpdateLastActive sets only the last active time so that it does not overwrite concurrent updates of the login.
+func UpdateLastActive(ctx context.Context, mongo odm.MongoClient, tenant, userId string, lastActive int64) error {
+	_, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$set": bson.M{"lastActive": lastActive}})
+	return err
 }
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// OtpChallengeModel holds an outstanding otp for an email or phone.
+// Only a salted hash of the code is stored. Otps delegated to a provider
+// store the provider's verification id instead.
+type OtpChallengeModel struct {
+	Identifier        string `bson:"_id"`
+	CodeHash          string `bson:"codeHash"`
+	Salt              string `bson:"salt"`
+	ProviderSid       string `bson:"providerSid"`
+	ExpiresOn         int64  `bson:"expiresOn"`
+	AttemptsRemaining int    `bson:"attemptsRemaining"`
+	// set when a provider verification is used, the record is kept so that replays are refused.
+	ConsumedOn int64 `bson:"consumedOn"`
+	CreatedOn  int64 `bson:"createdOn,omitempty"`
+}
+
+func (m OtpChallengeModel) Id() string { return m.Identifier }
+
+func (m OtpChallengeModel) CollectionName() string { return "otp_challenges" }
+
+// ConsumeOtpAttempt atomically decrements the attempts left on a challenge.
+// Returns false if the challenge does not exist or has no attempts left.
+func ConsumeOtpAttempt(ctx context.Context, mongo odm.MongoClient, tenant, identifier string) (bool, error) {
+	filter := bson.M{
+		"_id":               identifier,
+		"attemptsRemaining": bson.M{"$gt": 0},
+	}
+	update := bson.M{"$inc": bson.M{"attemptsRemaining": -1}}
+
+	res, err := mongo.Database(tenant).Collection(OtpChallengeModel{}.CollectionName()).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// ClaimOtpChallenge atomically consumes the challenge if it has not been replaced since it was read.
+// Only one of the concurrent callers holding the same challenge gets true.
+// Native challenges are removed. Provider challenges are marked consumed instead, since the
+// provider still accepts the code until the verification expires.
+func ClaimOtpChallenge(ctx context.Context, mongo odm.MongoClient, tenant string, challenge OtpChallengeModel) (bool, error) {
+	collection := mongo.Database(tenant).Collection(OtpChallengeModel{}.CollectionName())
+
+	if challenge.ProviderSid != "" {
+		filter := bson.M{
+			"_id":         challenge.Identifier,
+			"providerSid": challenge.ProviderSid,
+			"consumedOn":  bson.M{"$not": bson.M{"$gt": 0}},
+		}
+		res, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"consumedOn": time.Now().Unix()}})
+		if err != nil {
+			return false, err
+		}
+		return res.ModifiedCount == 1, nil
+	}
+
+	res, err := collection.DeleteOne(ctx, bson.M{"_id": challenge.Identifier, "codeHash": challenge.CodeHash})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RateLimitCounterModel counts hits of a key in a fixed window.
+// Documents are removed by a TTL index on expireAt.
+type RateLimitCounterModel struct {
+	Key      string    `bson:"_id"`
+	Count    int64     `bson:"count"`
+	ExpireAt time.Time `bson:"expireAt"`
+}
+
+func (m RateLimitCounterModel) Id() string { return m.Key }
+
+func (m RateLimitCounterModel) CollectionName() string { return "rate_limits" }
+
+// IncrementRateLimitCounter atomically increments the counter and returns the new count.
+func IncrementRateLimitCounter(ctx context.Context, mongo odm.MongoClient, tenant, key string, expireAt time.Time) (int64, error) {
+	update := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$setOnInsert": bson.M{"expireAt": expireAt},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	counter := &RateLimitCounterModel{}
+	err := mongo.Database(tenant).Collection(counter.CollectionName()).
+		FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).
+		Decode(counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Count, nil
+}
+
+// DecrementRateLimitCounter takes back a hit counted by IncrementRateLimitCounter, never going below 0.
+func DecrementRateLimitCounter(ctx context.Context, mongoClient odm.MongoClient, tenant, key string) error {
+	_, err := mongoClient.Database(tenant).Collection(RateLimitCounterModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": key, "count": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"count": -1}})
+	return err
+}
+
+// GetRateLimitCount returns the count of the key, 0 if it does not exist.
+func GetRateLimitCount(ctx context.Context, mongoClient odm.MongoClient, tenant, key string) (int64, error) {
+	counter := &RateLimitCounterModel{}
+	err := mongoClient.Database(tenant).Collection(counter.CollectionName()).
+		FindOne(ctx, bson.M{"_id": key}).
+		Decode(counter)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return counter.Count, nil
+}
+
+// EnsureExpiryIndex creates a TTL index on expireAt of the collection.
+func EnsureExpiryIndex(ctx context.Context, mongoClient odm.MongoClient, tenant, collection string) error {
+	index := mongo.IndexModel{
+		Keys:    bson.M{"expireAt": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := mongoClient.Database(tenant).Collection(collection).Indexes().CreateOne(ctx, index)
+	return err
+}
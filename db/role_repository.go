@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RoleModel is a named set of permissions of a tenant. Users get permissions
+// through the roles assigned to them in LoginModel.Roles.
+type RoleModel struct {
+	Name        string   `bson:"_id"`
+	Description string   `bson:"description"`
+	Permissions []string `bson:"permissions"`
+	CreatedBy   string   `bson:"createdBy"`
+	CreatedOn   int64    `bson:"createdOn,omitempty"`
+}
+
+func (m RoleModel) Id() string { return m.Name }
+
+func (m RoleModel) CollectionName() string { return "roles" }
+
+// AssignRole adds the role to the user. Returns false if the user does not exist.
+func AssignRole(ctx context.Context, mongo odm.MongoClient, tenant, userId, role string) (bool, error) {
+	res, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$addToSet": bson.M{"roles": role}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// UnassignRole removes the role from the user. Returns false if the user does not exist.
+func UnassignRole(ctx context.Context, mongo odm.MongoClient, tenant, userId, role string) (bool, error) {
+	res, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$pull": bson.M{"roles": role}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// UnassignRoleFromAll removes a deleted role from every user holding it.
+func UnassignRoleFromAll(ctx context.Context, mongo odm.MongoClient, tenant, role string) error {
+	_, err := mongo.Database(tenant).Collection(LoginModel{}.CollectionName()).
+		UpdateMany(ctx, bson.M{"roles": role}, bson.M{"$pull": bson.M{"roles": role}})
+	return err
+}
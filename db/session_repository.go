@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DeviceInfo struct {
+	UserAgent string `bson:"userAgent"`
+	Ip        string `bson:"ip"`
+}
+
+// SessionModel is a logged in device of a user.
+// Session is the refresh token family, every refresh rotates its token.
+type SessionModel struct {
+	SessionId        string `bson:"_id"`
+	UserId           string `bson:"userId"`
+	RefreshTokenHash string `bson:"refreshTokenHash"`
+	// hashes of refresh tokens already rotated, presenting one of them is a reuse.
+	RotatedTokenHashes []string   `bson:"rotatedTokenHashes"`
+	Device             DeviceInfo `bson:"device"`
+	LastRefreshedOn    int64      `bson:"lastRefreshedOn"`
+	ExpiresOn          int64      `bson:"expiresOn"`
+	RevokedOn          int64      `bson:"revokedOn"`
+	RevokeReason       string     `bson:"revokeReason"`
+	CreatedOn          int64      `bson:"createdOn,omitempty"`
+}
+
+func (m SessionModel) Id() string {
+	if m.SessionId == "" {
+		m.SessionId = uuid.New().String()
+	}
+	return m.SessionId
+}
+
+func (m SessionModel) CollectionName() string { return "sessions" }
+
+// RotateRefreshToken atomically replaces the current refresh token of an active session.
+// Returns nil if the session is revoked or the token is not the current one.
+func RotateRefreshToken(ctx context.Context, mongo odm.MongoClient, tenant, sessionId, currentHash, newHash string, now, expiresOn int64) (*SessionModel, error) {
+	filter := bson.M{
+		"_id":              sessionId,
+		"refreshTokenHash": currentHash,
+		"revokedOn":        0,
+		"expiresOn":        bson.M{"$gt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"refreshTokenHash": newHash,
+			"lastRefreshedOn":  now,
+			"expiresOn":        expiresOn,
+		},
+		// only recent tokens are kept, older ones are rejected without being detected as reuse.
+		"$push": bson.M{"rotatedTokenHashes": bson.M{"$each": bson.A{currentHash}, "$slice": -50}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	session := &SessionModel{}
+	err := mongo.Database(tenant).Collection(session.CollectionName()).
+		FindOneAndUpdate(ctx, filter, update, opts).
+		Decode(session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// RevokeSessions marks matching active sessions revoked.
+func RevokeSessions(ctx context.Context, mongo odm.MongoClient, tenant string, filter bson.M, reason string, now int64) (int64, error) {
+	filter["revokedOn"] = 0
+	update := bson.M{"$set": bson.M{"revokedOn": now, "revokeReason": reason}}
+
+	res, err := mongo.Database(tenant).Collection(SessionModel{}.CollectionName()).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// DeleteSessionsOfUser removes every session of the user, revoked or not.
+func DeleteSessionsOfUser(ctx context.Context, mongo odm.MongoClient, tenant, userId string) (int64, error) {
+	res, err := mongo.Database(tenant).Collection(SessionModel{}.CollectionName()).DeleteMany(ctx, bson.M{"userId": userId})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,18 @@
+package db
+
+// SigningKeyModel is a key pair used to sign access tokens of a tenant.
+// Stored in the tenant's database, private key is encrypted with the master key.
+type SigningKeyModel struct {
+	Kid                 string `bson:"_id"`
+	Algorithm           string `bson:"algorithm"`
+	EncryptedPrivateKey string `bson:"encryptedPrivateKey"`
+	// key signs new tokens from this time.
+	ActivatedOn int64 `bson:"activatedOn"`
+	// 0 while the key is current. Retired keys only verify tokens for a grace period.
+	RetiredOn int64 `bson:"retiredOn"`
+	CreatedOn int64 `bson:"createdOn,omitempty"`
+}
+
+func (m SigningKeyModel) Id() string { return m.Kid }
+
+func (m SigningKeyModel) CollectionName() string { return "signing_keys" }
@@ -0,0 +1,15 @@
+package db
+
+// SuperAdminModel grants a user of the platform tenant admin access to every tenant.
+// Stored in the control plane database, not in any tenant's login collection.
+type SuperAdminModel struct {
+	// user id in the platform tenant.
+	UserId    string `bson:"_id"`
+	Note      string `bson:"note"`
+	GrantedBy string `bson:"grantedBy"`
+	CreatedOn int64  `bson:"createdOn,omitempty"`
+}
+
+func (m SuperAdminModel) Id() string { return m.UserId }
+
+func (m SuperAdminModel) CollectionName() string { return "super_admins" }
@@ -0,0 +1,74 @@
+package db
+
+const (
+	TenantActive    = "active"
+	TenantSuspended = "suspended"
+	// deleted tenants are kept so that their name is not reused.
+	TenantDeleted = "deleted"
+)
+
+// TenantModel is a registered tenant. Stored in the control plane database.
+// Name is the domain clients send and the name of the tenant's database.
+type TenantModel struct {
+	Name        string            `bson:"_id"`
+	DisplayName string            `bson:"displayName"`
+	Status      string            `bson:"status"`
+	Settings    map[string]string `bson:"settings"`
+	Policy      TenantPolicy      `bson:"policy"`
+	CreatedBy   string            `bson:"createdBy"`
+	CreatedOn   int64             `bson:"createdOn,omitempty"`
+}
+
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite_only"
+
+	IdentifierPhone = "phone"
+	IdentifierEmail = "email"
+)
+
+// TenantPolicy decides how users of the tenant authenticate. Zero values take defaults.
+type TenantPolicy struct {
+	// open lets unknown emails/phones sign up, invite_only allows only existing logins.
+	Registration          string   `bson:"registration"`
+	AllowedIdentifiers    []string `bson:"allowedIdentifiers"`
+	OtpLength             int      `bson:"otpLength"`
+	OtpTtlSeconds         int      `bson:"otpTtlSeconds"`
+	ResendIntervalSeconds int      `bson:"resendIntervalSeconds"`
+	DailyOtpCap           int      `bson:"dailyOtpCap"`
+	SessionLifetimeHours  int      `bson:"sessionLifetimeHours"`
+	DefaultUserType       string   `bson:"defaultUserType"`
+}
+
+// WithDefaults returns the policy with unset values replaced by defaults.
+func (p TenantPolicy) WithDefaults() TenantPolicy {
+	if p.Registration == "" {
+		p.Registration = RegistrationOpen
+	}
+	if len(p.AllowedIdentifiers) == 0 {
+		p.AllowedIdentifiers = []string{IdentifierPhone, IdentifierEmail}
+	}
+	if p.OtpLength == 0 {
+		p.OtpLength = 6
+	}
+	if p.OtpTtlSeconds == 0 {
+		p.OtpTtlSeconds = 600
+	}
+	if p.ResendIntervalSeconds == 0 {
+		p.ResendIntervalSeconds = 60
+	}
+	if p.DailyOtpCap == 0 {
+		p.DailyOtpCap = 10
+	}
+	if p.SessionLifetimeHours == 0 {
+		p.SessionLifetimeHours = 30 * 24
+	}
+	if p.DefaultUserType == "" {
+		p.DefaultUserType = "member"
+	}
+	return p
+}
+
+func (m TenantModel) Id() string { return m.Name }
+
+func (m TenantModel) CollectionName() string { return "tenants" }
@@ -0,0 +1,19 @@
+package db
+
+// TestAccountModel is an email or phone that logs in with a static code
+// without an otp being sent. Used by app store reviewers and QA.
+type TestAccountModel struct {
+	// normalized email or E.164 phone.
+	Identifier  string `bson:"_id"`
+	CodeHash    string `bson:"codeHash"`
+	Salt        string `bson:"salt"`
+	ExpiresOn   int64  `bson:"expiresOn"`
+	UserType    string `bson:"userType"`
+	Description string `bson:"description"`
+	CreatedBy   string `bson:"createdBy"`
+	CreatedOn   int64  `bson:"createdOn,omitempty"`
+}
+
+func (m TestAccountModel) Id() string { return m.Identifier }
+
+func (m TestAccountModel) CollectionName() string { return "test_accounts" }
@@ -0,0 +1,21 @@
+package db
+
+// TwilioConfigModel is the tenant's Twilio configuration.
+// Auth token is a secret and is read from environment, never stored in db.
+type TwilioConfigModel struct {
+	AccountSid       string `bson:"accountSid"`
+	VerifyServiceSid string `bson:"verifyServiceSid"`
+	// used to deliver natively generated otps over sms.
+	MessagingServiceSid string `bson:"messagingServiceSid"`
+	FriendlyName        string `bson:"friendlyName"`
+	Locale              string `bson:"locale"`
+	// sms, whatsapp or voice in order of preference.
+	AllowedDeliveries []string `bson:"allowedDeliveries"`
+	// caller id for voice otps delivered natively.
+	VoiceCallerId string `bson:"voiceCallerId"`
+}
+
+// one config document per tenant database.
+func (m TwilioConfigModel) Id() string { return "twilio" }
+
+func (m TwilioConfigModel) CollectionName() string { return "otp_provider_config" }
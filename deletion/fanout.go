@@ -0,0 +1,201 @@
+package deletion
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Kotlang/authGo/appconfig"
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/extensions"
+	notificationPb "github.com/Kotlang/authGo/generated/notification"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+)
+
+const (
+	UserDeletedEvent = "user.deleted"
+	// unpublished events retried per tenant in a run.
+	publishBatchSize = 100
+	publishTimeout   = 30 * time.Second
+	// unpublished events whose user still exists after this long belong to a deletion which stopped half way.
+	abandonedEventAge = time.Hour
+)
+
+// EventSink delivers user.deleted events to downstream services.
+type EventSink interface {
+	Publish(ctx context.Context, tenant string, event db.DeletionEventModel) error
+}
+
+// NotificationSink publishes events through the notification service.
+type NotificationSink struct{}
+
+func (NotificationSink) Publish(ctx context.Context, tenant string, event db.DeletionEventModel) error {
+	errChan := extensions.PublishEvent(ctx, &notificationPb.RegisterEventRequest{
+		EventType: UserDeletedEvent,
+		Topic:     UserDeletedEvent,
+		TemplateParameters: map[string]string{
+			"eventId": event.EventId,
+			"userId":  event.UserId,
+			"tenant":  tenant,
+			"source":  event.Source,
+		},
+	})
+
+	// connecting to the notification service does not honour ctx.
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Fanout tells downstream services to remove data of deleted users and tracks their acknowledgements.
+// Events are saved before they are published and retried until published, so delivery is at least once
+// and subscribers must handle an event more than once.
+type Fanout struct {
+	mongo       odm.MongoClient
+	tenants     *tenant.Registry
+	sink        EventSink
+	subscribers []string
+}
+
+func ProvideFanout(mongo odm.MongoClient, ccfg *appconfig.AppConfig, tenants *tenant.Registry, sink EventSink) *Fanout {
+	subscribers := []string{}
+	for _, subscriber := range strings.Split(ccfg.DeletionSubscribers, ",") {
+		if subscriber = strings.TrimSpace(subscriber); subscriber != "" {
+			subscribers = append(subscribers, subscriber)
+		}
+	}
+
+	return &Fanout{
+		mongo:       mongo,
+		tenants:     tenants,
+		sink:        sink,
+		subscribers: subscribers,
+	}
+}
+
+// Subscribers returns services expected to acknowledge every event.
+func (f *Fanout) Subscribers() []string {
+	return f.subscribers
+}
+
+// Record saves a user.deleted event for the user before the user is deleted, so that the event is
+// never lost. Call Publish once the login is deleted, or Cancel if deleting the user failed.
+func (f *Fanout) Record(ctx context.Context, tenant, userId, source string) (*db.DeletionEventModel, error) {
+	event := db.DeletionEventModel{
+		UserId: userId,
+		Source: source,
+		Acks:   map[string]db.DeletionAck{},
+	}
+	event.EventId = event.Id()
+	for _, subscriber := range f.subscribers {
+		event.Acks[subscriber] = db.DeletionAck{Status: db.DeletionAckPending}
+	}
+
+	if _, err := async.Await(odm.CollectionOf[db.DeletionEventModel](f.mongo, tenant).Save(ctx, event)); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Publish publishes a recorded event in the background. Events which fail to publish are retried by PublishPending.
+func (f *Fanout) Publish(tenant string, event db.DeletionEventModel) {
+	// not bound to the caller's request, which may finish before the event is published.
+	go f.publish(context.Background(), tenant, event)
+}
+
+// Cancel keeps a recorded event from being published as the user was not deleted.
+func (f *Fanout) Cancel(ctx context.Context, tenant, eventId, reason string) {
+	if err := db.CancelDeletionEvent(ctx, f.mongo, tenant, eventId, reason, time.Now().Unix()); err != nil {
+		logger.Error("Failed cancelling deletion event", zap.String("tenant", tenant), zap.String("eventId", eventId), zap.Error(err))
+	}
+}
+
+// PublishPending retries events of every tenant which are not published yet.
+func (f *Fanout) PublishPending(ctx context.Context) {
+	tenants, err := f.tenants.List(ctx)
+	if err != nil {
+		logger.Error("Failed listing tenants", zap.Error(err))
+		return
+	}
+
+	for _, tenantInfo := range tenants {
+		events, err := async.Await(db.FindUnpublishedDeletionEvents(ctx, f.mongo, tenantInfo.Name, publishBatchSize))
+		if err != nil {
+			logger.Error("Failed finding unpublished deletion events", zap.String("tenant", tenantInfo.Name), zap.Error(err))
+			continue
+		}
+
+		for _, event := range events {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// only deletions which went through are published.
+			exists, err := async.Await(odm.CollectionOf[db.LoginModel](f.mongo, tenantInfo.Name).Exists(ctx, event.UserId))
+			if err != nil {
+				logger.Error("Failed checking deleted user", zap.String("tenant", tenantInfo.Name), zap.String("eventId", event.EventId), zap.Error(err))
+				continue
+			}
+			if exists {
+				if time.Since(time.Unix(event.CreatedOn, 0)) > abandonedEventAge {
+					f.Cancel(ctx, tenantInfo.Name, event.EventId, "user was not deleted")
+				}
+				continue
+			}
+
+			f.publish(ctx, tenantInfo.Name, event)
+		}
+	}
+}
+
+func (f *Fanout) publish(ctx context.Context, tenant string, event db.DeletionEventModel) {
+	publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+	err := f.sink.Publish(publishCtx, tenant, event)
+	cancel()
+	if err != nil {
+		logger.Error("Failed publishing deletion event", zap.String("tenant", tenant), zap.String("eventId", event.EventId), zap.Error(err))
+		err = db.RecordDeletionPublishFailure(ctx, f.mongo, tenant, event.EventId, err.Error())
+	} else {
+		err = db.MarkDeletionEventPublished(ctx, f.mongo, tenant, event.EventId, time.Now().Unix())
+	}
+
+	if err != nil {
+		logger.Error("Failed updating deletion event", zap.String("tenant", tenant), zap.String("eventId", event.EventId), zap.Error(err))
+	}
+}
+
+// Acknowledge records that the service removed, or failed to remove, its data of the deleted user.
+// Returns false if there is no such event or the service does not subscribe to it.
+func (f *Fanout) Acknowledge(ctx context.Context, tenant, eventId, service, status, details string) (bool, error) {
+	return db.AcknowledgeDeletionEvent(ctx, f.mongo, tenant, eventId, service, db.DeletionAck{
+		Status:         status,
+		Details:        details,
+		AcknowledgedOn: time.Now().Unix(),
+	})
+}
+
+// Status finds the deletion event by id, or the latest one of the user if eventId is empty.
+// Returns nil if there is none.
+func (f *Fanout) Status(ctx context.Context, tenant, userId, eventId string) (*db.DeletionEventModel, error) {
+	if eventId == "" {
+		return db.FindLatestDeletionEvent(ctx, f.mongo, tenant, userId)
+	}
+
+	filter := bson.M{"_id": eventId}
+	if userId != "" {
+		filter["userId"] = userId
+	}
+	events, err := async.Await(odm.CollectionOf[db.DeletionEventModel](f.mongo, tenant).Find(ctx, filter, nil, 1, 0))
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return &events[0], nil
+}
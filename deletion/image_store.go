@@ -0,0 +1,77 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Kotlang/authGo/appconfig"
+)
+
+// ImageStore removes uploaded profile images. cloud.Cloud can upload files but not delete them.
+type ImageStore interface {
+	// DeleteByPrefix deletes files of the bucket under prefix and returns the number deleted.
+	// With dryRun files are only counted.
+	DeleteByPrefix(ctx context.Context, bucket, prefix string, dryRun bool) (int, error)
+}
+
+// AzureImageStore deletes blobs of the storage account profile images are uploaded to.
+type AzureImageStore struct {
+	account string
+
+	once   sync.Once
+	client *azblob.Client
+	err    error
+}
+
+func ProvideAzureImageStore(ccfg *appconfig.AppConfig) *AzureImageStore {
+	return &AzureImageStore{account: ccfg.AzureStorageAccount}
+}
+
+func (s *AzureImageStore) DeleteByPrefix(ctx context.Context, bucket, prefix string, dryRun bool) (int, error) {
+	if err := s.ensureClient(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	pager := s.client.NewListBlobsFlatPager(bucket, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return count, err
+		}
+
+		for _, blob := range page.Segment.BlobItems {
+			if blob.Name == nil {
+				continue
+			}
+			if !dryRun {
+				if _, err := s.client.DeleteBlob(ctx, bucket, *blob.Name, nil); err != nil {
+					return count, err
+				}
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *AzureImageStore) ensureClient() error {
+	s.once.Do(func() {
+		if s.account == "" {
+			s.err = errors.New("azure_storage_account is not set")
+			return
+		}
+
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.client, s.err = azblob.NewClient(fmt.Sprintf("https://%s.blob.core.windows.net/", s.account), cred, nil)
+	})
+	return s.err
+}
@@ -0,0 +1,320 @@
+package deletion
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Kotlang/authGo/appconfig"
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/session"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	leaseName        = "deletion-worker"
+	defaultGraceDays = 30
+	defaultInterval  = time.Hour
+	actorId          = "deletion-worker"
+	// users purged per tenant in a run, the rest are picked up by following runs.
+	batchSize = 500
+	// holds base64 encoded key of at least 32 bytes hashing identifiers in tombstones.
+	tombstoneKeyEnv = "TOMBSTONE-HASH-KEY"
+)
+
+// Worker hard deletes accounts marked for deletion once their grace period is over
+// and removes data export archives whose download link expired.
+// Replicas compete for a lease in the control plane database so that only one of them purges at a time.
+type Worker struct {
+	mongo         odm.MongoClient
+	tenants       *tenant.Registry
+	sessions      *session.Store
+	images        ImageStore
+	fanout        *Fanout
+	database      string
+	profileBucket string
+	grace         time.Duration
+	interval      time.Duration
+	dryRun        bool
+	instanceId    string
+	tombstoneKey  []byte
+}
+
+func ProvideWorker(mongo odm.MongoClient, ccfg *appconfig.AppConfig, tenants *tenant.Registry, sessions *session.Store, images ImageStore, fanout *Fanout) (*Worker, error) {
+	tombstoneKey, err := base64.StdEncoding.DecodeString(os.Getenv(tombstoneKeyEnv))
+	if err != nil || len(tombstoneKey) < 32 {
+		return nil, errors.New(tombstoneKeyEnv + " should be set to a base64 encoded key of at least 32 bytes")
+	}
+
+	graceDays := ccfg.DeletionGraceDays
+	if graceDays <= 0 {
+		graceDays = defaultGraceDays
+	}
+
+	interval := defaultInterval
+	if ccfg.DeletionIntervalMinutes > 0 {
+		interval = time.Duration(ccfg.DeletionIntervalMinutes) * time.Minute
+	}
+
+	hostname, _ := os.Hostname()
+	return &Worker{
+		mongo:         mongo,
+		tenants:       tenants,
+		sessions:      sessions,
+		images:        images,
+		fanout:        fanout,
+		database:      ccfg.ControlPlaneDatabase(),
+		profileBucket: ccfg.ProfileBucket,
+		grace:         time.Duration(graceDays) * 24 * time.Hour,
+		interval:      interval,
+		dryRun:        ccfg.DeletionDryRun,
+		instanceId:    hostname + "/" + uuid.New().String(),
+		tombstoneKey:  tombstoneKey,
+	}, nil
+}
+
+// Run purges accounts every interval while this instance holds the lease, until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	logger.Info("Deletion worker started", zap.Duration("grace", w.grace), zap.Duration("interval", w.interval), zap.Bool("dryRun", w.dryRun))
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		leader, err := w.renewLease(ctx)
+		if err != nil {
+			logger.Error("Failed acquiring deletion lease", zap.Error(err))
+		} else if leader {
+			w.runOnce(ctx, w.dryRun)
+			w.fanout.PublishPending(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges accounts whose grace period is over right away and returns the number purged.
+// Fails if another instance holds the lease, so that it never purges alongside a running worker.
+// With dryRun nothing is deleted, accounts which would be purged are logged.
+func (w *Worker) RunOnce(ctx context.Context, dryRun bool) (int, error) {
+	leader, err := w.renewLease(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !leader {
+		return 0, errors.New("deletion worker lease is held by another instance")
+	}
+	defer w.release()
+
+	return w.runOnce(ctx, dryRun), nil
+}
+
+// runOnce purges accounts of every tenant whose grace period is over. The lease must be held.
+func (w *Worker) runOnce(ctx context.Context, dryRun bool) int {
+	tenants, err := w.tenants.List(ctx)
+	if err != nil {
+		logger.Error("Failed listing tenants", zap.Error(err))
+		return 0
+	}
+
+	before := time.Now().Add(-w.grace).Unix()
+	purged := 0
+	for _, tenantInfo := range tenants {
+		// a long run must not outlive the lease, or another replica starts purging alongside.
+		if leader, err := w.renewLease(ctx); err != nil || !leader {
+			logger.Error("Lost deletion lease, stopping run", zap.String("tenant", tenantInfo.Name), zap.Error(err))
+			return purged
+		}
+
+		logins, err := async.Await(db.FindLoginsPendingDeletion(ctx, w.mongo, tenantInfo.Name, before, batchSize))
+		if err != nil {
+			logger.Error("Failed finding accounts to purge", zap.String("tenant", tenantInfo.Name), zap.Error(err))
+			continue
+		}
+
+		for _, login := range logins {
+			if ctx.Err() != nil {
+				return purged
+			}
+
+			if err := w.purge(ctx, tenantInfo.Name, login, before, dryRun); err != nil {
+				logger.Error("Failed purging account", zap.String("tenant", tenantInfo.Name), zap.String("userId", login.UserId), zap.Error(err))
+				continue
+			}
+			purged++
+		}
+
+		w.purgeExpiredExports(ctx, tenantInfo.Name, dryRun)
+	}
+
+	logger.Info("Deletion run finished", zap.Int("purged", purged), zap.Bool("dryRun", dryRun))
+	return purged
+}
+
+// purge claims the login so that it can no longer be restored, then deletes everything of the user.
+func (w *Worker) purge(ctx context.Context, tenant string, login db.LoginModel, before int64, dryRun bool) error {
+	if dryRun {
+		leads, err := db.CountLeadsByPhone(ctx, w.mongo, tenant, login.Phone)
+		if err != nil {
+			return err
+		}
+		images, err := w.deleteImages(ctx, tenant, login.UserId, true)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Dry run: would purge account",
+			zap.String("tenant", tenant),
+			zap.String("userId", login.UserId),
+			zap.Int64("requestedOn", login.DeletionInfo.DeletionTime),
+			zap.Int64("leads", leads),
+			zap.Int("images", images))
+		return nil
+	}
+
+	// claimed before anything is deleted, a user logging back in after this can no longer restore the account.
+	claimed, err := db.ClaimLoginForPurge(ctx, w.mongo, tenant, login.UserId, before)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		logger.Info("Account restored before purge", zap.String("tenant", tenant), zap.String("userId", login.UserId))
+		return nil
+	}
+
+	// saved before anything is deleted so that downstream services always hear of the purge.
+	event, err := w.fanout.Record(ctx, tenant, login.UserId, db.DeletionSourceGracePeriod)
+	if err != nil {
+		return err
+	}
+
+	images, leads, err := w.deleteAccount(ctx, tenant, login)
+	if err != nil {
+		w.fanout.Cancel(ctx, tenant, event.EventId, err.Error())
+		return err
+	}
+	w.fanout.Publish(tenant, *event)
+
+	tombstone := db.DeletionTombstoneModel{
+		UserId:        login.UserId,
+		RequestedOn:   login.DeletionInfo.DeletionTime,
+		LeadsDeleted:  leads,
+		ImagesDeleted: images,
+	}
+	for _, identifier := range []string{login.Email, login.Phone} {
+		if identifier != "" {
+			tombstone.IdentifierHashes = append(tombstone.IdentifierHashes, db.HashIdentifier(w.tombstoneKey, identifier))
+		}
+	}
+	if _, err := async.Await(odm.CollectionOf[db.DeletionTombstoneModel](w.mongo, tenant).Save(ctx, tombstone)); err != nil {
+		logger.Error("Failed saving deletion tombstone", zap.String("tenant", tenant), zap.String("userId", login.UserId), zap.Error(err))
+	}
+
+	db.SaveAuditLog(ctx, w.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditUserPurged,
+		ActorId: actorId,
+		Target:  login.UserId,
+		Details: map[string]string{
+			"leads":  strconv.FormatInt(leads, 10),
+			"images": strconv.Itoa(images),
+		},
+	})
+	return nil
+}
+
+// deleteAccount deletes the user's images, leads, profile, sessions and finally the login.
+// The login must be claimed for purge, it is kept on failure so that the next run retries.
+func (w *Worker) deleteAccount(ctx context.Context, tenant string, login db.LoginModel) (int, int64, error) {
+	images, err := w.deleteImages(ctx, tenant, login.UserId, false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	leads, err := db.DeleteLeadsByPhone(ctx, w.mongo, tenant, login.Phone)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := async.Await(odm.CollectionOf[db.ProfileModel](w.mongo, tenant).DeleteByID(ctx, login.UserId)); err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := w.sessions.DeleteAll(ctx, tenant, login.UserId); err != nil {
+		return 0, 0, err
+	}
+
+	if err := db.DeletePurgedLogin(ctx, w.mongo, tenant, login.UserId); err != nil {
+		return 0, 0, err
+	}
+	return images, leads, nil
+}
+
+// purgeExpiredExports removes data export archives whose download link expired, downloaded or not.
+// Skipped when no image store or profile bucket is configured.
+func (w *Worker) purgeExpiredExports(ctx context.Context, tenant string, dryRun bool) {
+	if w.images == nil || w.profileBucket == "" {
+		return
+	}
+
+	exports, err := async.Await(db.FindExpiredDataExports(ctx, w.mongo, tenant, time.Now().Unix(), batchSize))
+	if err != nil {
+		logger.Error("Failed finding expired data exports", zap.String("tenant", tenant), zap.Error(err))
+		return
+	}
+
+	for _, export := range exports {
+		if _, err := w.images.DeleteByPrefix(ctx, w.profileBucket, export.Path, dryRun); err != nil {
+			logger.Error("Failed deleting expired data export", zap.String("tenant", tenant), zap.String("exportId", export.ExportId), zap.Error(err))
+			continue
+		}
+		if dryRun {
+			logger.Info("Dry run: would delete expired data export", zap.String("tenant", tenant), zap.String("exportId", export.ExportId))
+			continue
+		}
+		if _, err := async.Await(odm.CollectionOf[db.DataExportModel](w.mongo, tenant).DeleteByID(ctx, export.ExportId)); err != nil {
+			logger.Error("Failed deleting data export", zap.String("tenant", tenant), zap.String("exportId", export.ExportId), zap.Error(err))
+		}
+	}
+}
+
+// deleteImages removes profile images uploaded by the user.
+// Skipped when no image store or profile bucket is configured.
+func (w *Worker) deleteImages(ctx context.Context, tenant, userId string, dryRun bool) (int, error) {
+	if w.images == nil || w.profileBucket == "" {
+		return 0, nil
+	}
+
+	// same layout as profile image uploads: <tenant>/<userId>/<time>.<ext>
+	prefix := fmt.Sprintf("%s/%s/", tenant, userId)
+	return w.images.DeleteByPrefix(ctx, w.profileBucket, prefix, dryRun)
+}
+
+// renewLease takes the lease or extends it if this instance holds it.
+// The lease outlives a missed tick, so a crashed leader is replaced within two intervals.
+func (w *Worker) renewLease(ctx context.Context) (bool, error) {
+	return db.AcquireLease(ctx, w.mongo, w.database, leaseName, w.instanceId, 2*w.interval)
+}
+
+// release hands the lease over on shutdown instead of making other replicas wait for its expiry.
+func (w *Worker) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.ReleaseLease(ctx, w.mongo, w.database, leaseName, w.instanceId); err != nil {
+		logger.Error("Failed releasing deletion lease", zap.Error(err))
+	}
+}
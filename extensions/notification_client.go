@@ -72,6 +72,31 @@ func RegisterEvent(grpcContext context.Context, event *notificationPb.RegisterEv
 	return errChan
 }
 
+// PublishEvent registers an event raised by authGo itself rather than on behalf of a user.
+// It authenticates with client credentials, secret is read from NOTIFICATION-CLIENT-SECRET env variable.
+func PublishEvent(grpcContext context.Context, event *notificationPb.RegisterEventRequest) chan error {
+	// buffered so that the goroutine finishes when the caller stops waiting.
+	errChan := make(chan error, 1)
+
+	go func() {
+		conn := notification_client.getNotificationConnection()
+		if conn == nil {
+			errChan <- errors.New("Failed to get connection with notification service")
+			return
+		}
+
+		client := notificationPb.NewNotificationServiceClient(conn)
+		ctx := metadata.AppendToOutgoingContext(grpcContext,
+			"x-client-id", "auth",
+			"x-client-secret", os.Getenv("NOTIFICATION-CLIENT-SECRET"))
+
+		_, err := client.RegisterEvent(ctx, event)
+		errChan <- err
+	}()
+
+	return errChan
+}
+
 func prepareCallContext(grpcContext context.Context) context.Context {
 	jwtToken, err := grpc_auth.AuthFromMD(grpcContext, "bearer")
 	if err != nil {
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/Kotlang/authGo/db"
-	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/Kotlang/authGo/session"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/Kotlang/authGo/token"
 	"github.com/SaiNageswarS/go-api-boot/auth"
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
@@ -19,8 +21,8 @@ type ServiceCheckUserExistenceInterceptor interface {
 	CheckUserExistenceOverride(ctx context.Context) (context.Context, error)
 }
 
-// checks if the user exists and updates the last active time of the user
-func UserExistsAndUpdateLastActiveUnaryInterceptor(mongo odm.MongoClient) grpc.UnaryServerInterceptor {
+// checks if the session is active, the user exists and updates the last active time of the user
+func UserExistsAndUpdateLastActiveUnaryInterceptor(mongo odm.MongoClient, sessions *session.Store, tenants *tenant.Registry) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 
 		// check if the service has overridden the interceptor
@@ -33,36 +35,62 @@ func UserExistsAndUpdateLastActiveUnaryInterceptor(mongo odm.MongoClient) grpc.U
 			return handler(ctx, req)
 		}
 
-		userId, tenant := auth.GetUserIdAndTenant(ctx)
-		login, err := async.Await(odm.CollectionOf[db.LoginModel](mongo, tenant).FindOneByID(ctx, userId))
-		if err != nil {
-			logger.Error("User not found", zap.String("userId", userId), zap.Error(err))
-		} else {
-			if err := checkUserExistenceAndStatus(login); err != nil {
-				return nil, err
-			}
+		if err := checkUserAccess(ctx, mongo, sessions, tenants); err != nil {
+			return nil, err
+		}
+
+		resp, err := handler(ctx, req)
+		return resp, err
+	}
+}
 
-			login.LastActive = time.Now().Unix()
-			_, err = async.Await(odm.CollectionOf[db.LoginModel](mongo, tenant).Save(ctx, *login))
+// same checks as the unary interceptor for streaming rpcs like profile image upload.
+func UserExistsAndUpdateLastActiveStreamInterceptor(mongo odm.MongoClient, sessions *session.Store, tenants *tenant.Registry) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if overrideService, ok := srv.(ServiceCheckUserExistenceInterceptor); ok {
+			newCtx, err := overrideService.CheckUserExistenceOverride(ss.Context())
 			if err != nil {
-				logger.Error("Error updating last active time", zap.String("userId", userId), zap.Error(err))
+				return err
 			}
+			return handler(srv, &contextServerStream{ServerStream: ss, ctx: newCtx})
 		}
 
-		resp, err := handler(ctx, req)
-		return resp, err
+		if err := checkUserAccess(ss.Context(), mongo, sessions, tenants); err != nil {
+			return err
+		}
+		return handler(srv, ss)
 	}
 }
 
-func checkUserExistenceAndStatus(loginInfo *db.LoginModel) error {
-	if loginInfo.IsBlocked {
-		logger.Error("User is blocked", zap.String("userId", loginInfo.UserId))
-		return status.Error(codes.PermissionDenied, "User is blocked")
+func checkUserAccess(ctx context.Context, mongo odm.MongoClient, sessions *session.Store, tenants *tenant.Registry) error {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	claims, err := token.ClaimsFromContext(ctx)
+	if err != nil {
+		logger.Error("Failed reading token claims", zap.Error(err))
+		return status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	if err := tenants.CheckActive(ctx, tenant); err != nil {
+		return err
+	}
+
+	if _, err := sessions.CheckAccess(ctx, tenant, claims); err != nil {
+		return err
 	}
 
-	if loginInfo.DeletionInfo.MarkedForDeletion {
-		logger.Error("User is marked for deletion", zap.String("userId", loginInfo.UserId))
-		return status.Error(codes.PermissionDenied, "User is marked for deletion")
+	err = db.UpdateLastActive(ctx, mongo, tenant, userId, time.Now().Unix())
+	if err != nil {
+		logger.Error("Error updating last active time", zap.String("userId", userId), zap.Error(err))
 	}
 	return nil
 }
+
+// contextServerStream replaces the context of a stream.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,47 @@
+package interceptors
+
+import (
+	"context"
+
+	"github.com/Kotlang/authGo/rbac"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rejects calls the method's policy does not allow before the handler runs.
+// Methods without a policy are rejected.
+func AuthorizationUnaryInterceptor(policies *rbac.PolicyRegistry, authz *rbac.Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, policies, authz, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func AuthorizationStreamInterceptor(policies *rbac.PolicyRegistry, authz *rbac.Authorizer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(ss.Context(), policies, authz, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func authorize(ctx context.Context, policies *rbac.PolicyRegistry, authz *rbac.Authorizer, fullMethod string) error {
+	policy, ok := policies.Get(fullMethod)
+	if !ok {
+		logger.Error("No authorization policy for method", zap.String("method", fullMethod))
+		return status.Error(codes.PermissionDenied, "Method is not allowed")
+	}
+
+	if policy.Public || policy.Permission == "" {
+		return nil
+	}
+
+	_, _, err := authz.Authorize(ctx, policy.Permission)
+	return err
+}
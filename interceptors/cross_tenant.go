@@ -0,0 +1,116 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Kotlang/authGo/apierror"
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/rbac"
+	"github.com/Kotlang/authGo/superadmin"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// lets super admins call admin rpcs against the tenant named in x-target-tenant header.
+// The handler sees the target tenant as caller's tenant. Every call is audited in both tenants.
+// Must run after the session check of the caller's own tenant.
+func CrossTenantUnaryInterceptor(mongo odm.MongoClient, policies *rbac.PolicyRegistry, superAdmins *superadmin.Directory, tenants *tenant.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		targetTenant := getTargetTenant(ctx)
+		userId, homeTenant := auth.GetUserIdAndTenant(ctx)
+		if targetTenant == "" || targetTenant == homeTenant {
+			return handler(ctx, req)
+		}
+
+		call := superadmin.Call{UserId: userId, HomeTenant: homeTenant, TargetTenant: targetTenant}
+		if err := checkCrossTenantCall(ctx, policies, superAdmins, tenants, call, info.FullMethod); err != nil {
+			auditCrossTenantCall(ctx, mongo, call, info.FullMethod, err, homeTenant)
+			return nil, err
+		}
+
+		logger.Info("Cross tenant call", zap.String("userId", userId), zap.String("targetTenant", targetTenant), zap.String("method", info.FullMethod))
+
+		targetCtx := context.WithValue(ctx, auth.TENANT_CLAIM, targetTenant)
+		targetCtx = superadmin.WithCall(targetCtx, call)
+		resp, err := handler(targetCtx, req)
+
+		auditCrossTenantCall(ctx, mongo, call, info.FullMethod, err, homeTenant, targetTenant)
+		return resp, err
+	}
+}
+
+// streaming rpcs are not admin rpcs, targeting another tenant is rejected.
+func CrossTenantStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		targetTenant := getTargetTenant(ss.Context())
+		_, homeTenant := auth.GetUserIdAndTenant(ss.Context())
+		if targetTenant != "" && targetTenant != homeTenant {
+			return status.Error(codes.InvalidArgument, "Only admin rpcs can target another tenant")
+		}
+		return handler(srv, ss)
+	}
+}
+
+func checkCrossTenantCall(ctx context.Context, policies *rbac.PolicyRegistry, superAdmins *superadmin.Directory, tenants *tenant.Registry, call superadmin.Call, fullMethod string) error {
+	policy, ok := policies.Get(fullMethod)
+	if !ok || policy.Public || policy.Permission == "" {
+		return status.Error(codes.InvalidArgument, "Only admin rpcs can target another tenant")
+	}
+
+	isSuperAdmin, err := superAdmins.IsSuperAdmin(ctx, call.HomeTenant, call.UserId)
+	if err != nil {
+		logger.Error("Failed checking super admin", zap.String("userId", call.UserId), zap.Error(err))
+		return status.Error(codes.Internal, "Failed checking permission")
+	}
+	if !isSuperAdmin {
+		logger.Error("Cross tenant call by non super admin", zap.String("userId", call.UserId), zap.String("tenant", call.HomeTenant), zap.String("targetTenant", call.TargetTenant))
+		return status.Error(codes.PermissionDenied, "User with id "+call.UserId+" can't act on other tenants")
+	}
+
+	// suspended tenants can still be administered.
+	target, err := tenants.Get(ctx, call.TargetTenant)
+	if err != nil {
+		logger.Error("Failed getting tenant", zap.String("tenant", call.TargetTenant), zap.Error(err))
+		return status.Error(codes.Unavailable, "Failed checking tenant")
+	}
+	if target == nil || target.Status == db.TenantDeleted {
+		return apierror.New(codes.NotFound, apierror.ReasonTenantUnknown, "Unknown target tenant", 0, nil)
+	}
+	return nil
+}
+
+// audit entries are written to each of the tenants.
+func auditCrossTenantCall(ctx context.Context, mongo odm.MongoClient, call superadmin.Call, fullMethod string, err error, auditTenants ...string) {
+	for _, auditTenant := range auditTenants {
+		db.SaveAuditLog(ctx, mongo, auditTenant, db.AuditLogModel{
+			Action:      db.AuditCrossTenantCall,
+			ActorId:     call.UserId,
+			ActorTenant: call.HomeTenant,
+			CrossTenant: true,
+			Target:      call.TargetTenant,
+			Details: map[string]string{
+				"method": fullMethod,
+				"code":   status.Code(err).String(),
+			},
+		})
+	}
+}
+
+func getTargetTenant(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(superadmin.TargetTenantHeader); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
@@ -2,12 +2,23 @@ package main
 
 import (
 	"context"
+	"os"
+	"strings"
 
 	"github.com/Kotlang/authGo/appconfig"
+	"github.com/Kotlang/authGo/dataexport"
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/deletion"
 	authPb "github.com/Kotlang/authGo/generated/auth"
 	"github.com/Kotlang/authGo/interceptors"
 	"github.com/Kotlang/authGo/otp"
+	"github.com/Kotlang/authGo/ratelimit"
+	"github.com/Kotlang/authGo/rbac"
 	"github.com/Kotlang/authGo/service"
+	"github.com/Kotlang/authGo/session"
+	"github.com/Kotlang/authGo/superadmin"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/Kotlang/authGo/token"
 	"github.com/SaiNageswarS/go-api-boot/cloud"
 	"github.com/SaiNageswarS/go-api-boot/config"
 	"github.com/SaiNageswarS/go-api-boot/dotenv"
@@ -21,10 +32,18 @@ import (
 
 func main() {
 	dotenv.LoadEnv()
-	cloudFns := &cloud.Azure{}
 
 	ccfgg := &appconfig.AppConfig{}
 	config.LoadConfig("config.ini", ccfgg)
+	// blob storage needs the storage account from config.
+	cloudFns := cloud.ProvideAzure(&ccfgg.BootConfig)
+
+	if err := ratelimit.TrustProxies(ccfgg.TrustedProxies); err != nil {
+		logger.Fatal("Invalid trusted_proxies", zap.Error(err))
+	}
+	if !ratelimit.ProxiesTrusted() {
+		logger.Get().Warn("trusted_proxies is not set, per ip rate limits are off")
+	}
 
 	mongoClient, err := mongo.Connect(context.Background(), options.Client().ApplyURI(ccfgg.MongoURI))
 	if err != nil {
@@ -33,7 +52,116 @@ func main() {
 
 	logger.Info("MongoDB connected")
 
-	otpClient := &otp.DevOtpClient{}
+	tenantRegistry := tenant.ProvideRegistry(mongoClient, ccfgg)
+
+	keyStore, err := token.ProvideKeyStore(mongoClient, tenantRegistry)
+	if err != nil {
+		logger.Fatal("Failed to create key store", zap.Error(err))
+	}
+
+	// admin command to register a tenant, needed to bootstrap the platform tenant: authGo create-tenant <tenant> [display name]
+	if len(os.Args) > 1 && os.Args[1] == "create-tenant" {
+		if len(os.Args) < 3 {
+			logger.Fatal("Usage: create-tenant <tenant> [display name]")
+		}
+		if !tenant.IsValidName(os.Args[2]) {
+			logger.Fatal("Invalid tenant name", zap.String("tenant", os.Args[2]))
+		}
+		displayName := strings.Join(os.Args[3:], " ")
+		_, err := tenantRegistry.Create(context.Background(), db.TenantModel{Name: os.Args[2], DisplayName: displayName, CreatedBy: "cli"})
+		if err != nil {
+			logger.Fatal("Failed creating tenant", zap.Error(err))
+		}
+		logger.Info("Tenant created", zap.String("tenant", os.Args[2]))
+		return
+	}
+
+	superAdmins := superadmin.ProvideDirectory(mongoClient, ccfgg)
+
+	// admin commands to manage platform super admins: authGo grant-super-admin <userId> [note], authGo revoke-super-admin <userId>
+	if len(os.Args) > 1 && (os.Args[1] == "grant-super-admin" || os.Args[1] == "revoke-super-admin") {
+		if len(os.Args) < 3 {
+			logger.Fatal("Usage: " + os.Args[1] + " <platform tenant user id>")
+		}
+		if ccfgg.PlatformTenant == "" {
+			logger.Fatal("platform_tenant is not set")
+		}
+
+		userId := os.Args[2]
+		action := db.AuditSuperAdminGranted
+		if os.Args[1] == "grant-super-admin" {
+			err = superAdmins.Grant(context.Background(), userId, strings.Join(os.Args[3:], " "), "cli")
+		} else {
+			action = db.AuditSuperAdminRevoked
+			_, err = superAdmins.Revoke(context.Background(), userId)
+		}
+		if err != nil {
+			logger.Fatal("Failed updating super admin", zap.Error(err))
+		}
+
+		db.SaveAuditLog(context.Background(), mongoClient, ccfgg.PlatformTenant, db.AuditLogModel{
+			Action:  action,
+			ActorId: "cli",
+			Target:  userId,
+		})
+		logger.Info("Super admin updated", zap.String("command", os.Args[1]), zap.String("userId", userId))
+		return
+	}
+
+	// admin command to rotate a tenant's access token signing key: authGo rotate-signing-key <tenant>
+	if len(os.Args) > 1 && os.Args[1] == "rotate-signing-key" {
+		if len(os.Args) < 3 {
+			logger.Fatal("Usage: rotate-signing-key <tenant>")
+		}
+		kid, err := keyStore.Rotate(context.Background(), os.Args[2])
+		if err != nil {
+			logger.Fatal("Failed rotating signing key", zap.Error(err))
+		}
+		logger.Info("Signing key rotated", zap.String("tenant", os.Args[2]), zap.String("kid", kid))
+		return
+	}
+
+	otpClient, err := otp.ProvideOtpClientForMode(mongoClient, ccfgg)
+	if err != nil {
+		logger.Fatal("Failed to create otp client", zap.Error(err))
+	}
+
+	if _, isDev := otpClient.(*otp.DevOtpClient); isDev {
+		logger.Get().Warn("!!! OTP MODE IS DEV: EVERY OTP IS ACCEPTED. NEVER RUN THIS IN PRODUCTION !!!", zap.String("otpMode", ccfgg.OtpMode))
+	} else {
+		logger.Info("OTP mode", zap.String("otpMode", ccfgg.OtpMode))
+	}
+
+	sessionStore := session.ProvideStore(mongoClient)
+
+	// profile images are only deleted when the storage account they are uploaded to is known.
+	var profileImages deletion.ImageStore
+	if ccfgg.AzureStorageAccount != "" {
+		profileImages = deletion.ProvideAzureImageStore(ccfgg)
+	}
+	deletionFanout := deletion.ProvideFanout(mongoClient, ccfgg, tenantRegistry, deletion.NotificationSink{})
+	exporter, err := dataexport.ProvideExporter(mongoClient, ccfgg, cloudFns, profileImages)
+	if err != nil {
+		logger.Fatal("Failed to create data exporter", zap.Error(err))
+	}
+	deletionWorker, err := deletion.ProvideWorker(mongoClient, ccfgg, tenantRegistry, sessionStore, profileImages, deletionFanout)
+	if err != nil {
+		logger.Fatal("Failed to create deletion worker", zap.Error(err))
+	}
+
+	// admin command to purge accounts past the deletion grace period right away: authGo purge-deleted-accounts [--dry-run]
+	if len(os.Args) > 1 && os.Args[1] == "purge-deleted-accounts" {
+		dryRun := len(os.Args) > 2 && os.Args[2] == "--dry-run"
+		purged, err := deletionWorker.RunOnce(context.Background(), dryRun)
+		if err != nil {
+			logger.Fatal("Failed purging deleted accounts", zap.Error(err))
+		}
+		logger.Info("Purged deleted accounts", zap.Int("purged", purged), zap.Bool("dryRun", dryRun))
+		return
+	}
+
+	authorizer := rbac.ProvideAuthorizer(mongoClient)
+	policies := rbac.ProvidePolicyRegistry(service.MethodPolicies())
 
 	boot, err := server.New().
 		GRPCPort(":50051").
@@ -43,21 +171,47 @@ func main() {
 		ProvideAs(cloudFns, (*cloud.Cloud)(nil)).
 		ProvideAs(mongoClient, (*odm.MongoClient)(nil)).
 		ProvideAs(otpClient, (*otp.OtpClientInterface)(nil)).
+		Provide(sessionStore).
+		Provide(keyStore).
+		Provide(tenantRegistry).
+		Provide(authorizer).
+		Provide(deletionFanout).
+		Provide(exporter).
 		// Custom Interceptors
-		Unary(interceptors.UserExistsAndUpdateLastActiveUnaryInterceptor(mongoClient)).
+		Unary(interceptors.UserExistsAndUpdateLastActiveUnaryInterceptor(mongoClient, sessionStore, tenantRegistry)).
+		Unary(interceptors.CrossTenantUnaryInterceptor(mongoClient, policies, superAdmins, tenantRegistry)).
+		Unary(interceptors.AuthorizationUnaryInterceptor(policies, authorizer)).
+		Stream(interceptors.UserExistsAndUpdateLastActiveStreamInterceptor(mongoClient, sessionStore, tenantRegistry)).
+		Stream(interceptors.CrossTenantStreamInterceptor()).
+		Stream(interceptors.AuthorizationStreamInterceptor(policies, authorizer)).
+		// public keys for services verifying access tokens
+		Handle(token.JwksPath, keyStore.JwksHandler()).
+		// single use download links of personal data exports
+		Handle(dataexport.DownloadPath, exporter.DownloadHandler()).
 		// Register gRPC service impls
-		RegisterService(server.Adapt(authPb.RegisterLoginServer), service.ProvideLoginService).
-		RegisterService(server.Adapt(authPb.RegisterLoginVerifiedServer), service.ProvideLoginVerifiedService).
-		RegisterService(server.Adapt(authPb.RegisterProfileServer), service.ProvideProfileService).
-		RegisterService(server.Adapt(authPb.RegisterProfileMasterServer), service.ProvideProfileMasterService).
-		RegisterService(server.Adapt(authPb.RegisterLeadServiceServer), service.ProvideLeadService).
+		RegisterService(policies.Track(server.Adapt(authPb.RegisterLoginServer)), service.ProvideLoginService).
+		RegisterService(policies.Track(server.Adapt(authPb.RegisterLoginVerifiedServer)), service.ProvideLoginVerifiedService).
+		RegisterService(policies.Track(server.Adapt(authPb.RegisterProfileServer)), service.ProvideProfileService).
+		RegisterService(policies.Track(server.Adapt(authPb.RegisterProfileMasterServer)), service.ProvideProfileMasterService).
+		RegisterService(policies.Track(server.Adapt(authPb.RegisterLeadServiceServer)), service.ProvideLeadService).
+		RegisterService(policies.Track(server.Adapt(authPb.RegisterIntrospectionServer)), service.ProvideIntrospectionService).
+		RegisterService(policies.Track(server.Adapt(authPb.RegisterTenantServer)), service.ProvideTenantService).
+		RegisterService(policies.Track(server.Adapt(authPb.RegisterRoleServer)), service.ProvideRoleService).
+		RegisterService(policies.Track(server.Adapt(authPb.RegisterDeletionAckServer)), service.ProvideDeletionAckService).
 		Build()
 
 	if err != nil {
 		logger.Fatal("Failed to create server", zap.Error(err))
 	}
 
-	ctx, _ := context.WithCancel(context.Background())
+	// every registered rpc must declare who can call it.
+	if err := policies.Verify(); err != nil {
+		logger.Fatal("Missing authorization policies", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go deletionWorker.Run(ctx)
 	boot.Serve(ctx)
 	logger.Info("Server shutdown cleanly")
 }
@@ -0,0 +1,44 @@
+package otp
+
+import (
+	"context"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+)
+
+// challengeStore keeps the outstanding otp challenges of native and provider verifications.
+type challengeStore interface {
+	Get(ctx context.Context, tenant, identifier string) (*db.OtpChallengeModel, error)
+	Save(ctx context.Context, tenant string, challenge db.OtpChallengeModel) error
+	Delete(ctx context.Context, tenant, identifier string) error
+	ConsumeAttempt(ctx context.Context, tenant, identifier string) (bool, error)
+	Claim(ctx context.Context, tenant string, challenge db.OtpChallengeModel) (bool, error)
+}
+
+type mongoChallengeStore struct {
+	mongo odm.MongoClient
+}
+
+func (s mongoChallengeStore) Get(ctx context.Context, tenant, identifier string) (*db.OtpChallengeModel, error) {
+	return async.Await(odm.CollectionOf[db.OtpChallengeModel](s.mongo, tenant).FindOneByID(ctx, identifier))
+}
+
+func (s mongoChallengeStore) Save(ctx context.Context, tenant string, challenge db.OtpChallengeModel) error {
+	_, err := async.Await(odm.CollectionOf[db.OtpChallengeModel](s.mongo, tenant).Save(ctx, challenge))
+	return err
+}
+
+func (s mongoChallengeStore) Delete(ctx context.Context, tenant, identifier string) error {
+	_, err := async.Await(odm.CollectionOf[db.OtpChallengeModel](s.mongo, tenant).DeleteByID(ctx, identifier))
+	return err
+}
+
+func (s mongoChallengeStore) ConsumeAttempt(ctx context.Context, tenant, identifier string) (bool, error) {
+	return db.ConsumeOtpAttempt(ctx, s.mongo, tenant, identifier)
+}
+
+func (s mongoChallengeStore) Claim(ctx context.Context, tenant string, challenge db.OtpChallengeModel) (bool, error) {
+	return db.ClaimOtpChallenge(ctx, s.mongo, tenant, challenge)
+}
@@ -0,0 +1,56 @@
+package otp
+
+import (
+	"slices"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mediums over which a phone otp can be delivered.
+const (
+	DeliverySms      = "sms"
+	DeliveryWhatsapp = "whatsapp"
+	DeliveryVoice    = "voice"
+)
+
+// used when tenant has not configured allowed deliveries.
+var defaultDeliveries = []string{DeliverySms}
+
+// Implemented by channels that can deliver otp over more than one medium.
+type MultiDeliveryChannel interface {
+	// ordered by preference, a quick resend escalates to the next one.
+	AllowedDeliveries(tenant string) []string
+}
+
+// selectDelivery returns the requested delivery if allowed, defaulting to the first allowed.
+// A resend within escalation window on the same delivery escalates to the next allowed one.
+func selectDelivery(allowed []string, requested string, loginInfo *db.LoginModel, escalationWindow time.Duration) (string, error) {
+	if len(allowed) == 0 {
+		allowed = defaultDeliveries
+	}
+
+	if requested != "" && !slices.Contains(allowed, requested) {
+		return "", status.Error(codes.InvalidArgument, "Otp delivery over "+requested+" is not allowed")
+	}
+
+	delivery := requested
+	if delivery == "" {
+		delivery = allowed[0]
+	}
+
+	lastDelivery := loginInfo.LastOtpDelivery
+	isQuickResend := time.Now().Unix()-loginInfo.LastOtpSentTime < int64(escalationWindow.Seconds())
+	if lastDelivery != "" && isQuickResend && (requested == "" || requested == lastDelivery) {
+		idx := slices.Index(allowed, lastDelivery)
+		if idx >= 0 && idx+1 < len(allowed) {
+			delivery = allowed[idx+1]
+		} else if idx >= 0 {
+			delivery = lastDelivery
+		}
+	}
+
+	return delivery, nil
+}
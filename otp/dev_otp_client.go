@@ -4,7 +4,7 @@ import "github.com/Kotlang/authGo/db"
 
 type DevOtpClient struct{}
 
-func (s *DevOtpClient) SendOtp(tenant, to string) error {
+func (s *DevOtpClient) SendOtp(tenant, to, delivery string, policy db.TenantPolicy) error {
 	return nil
 }
 
@@ -2,23 +2,28 @@ package otp
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/async"
 	"github.com/SaiNageswarS/go-api-boot/odm"
 )
 
 type EmailClientInterface interface {
 	IsValid(emailOrPhone string) bool
-	SendOtp(emailId string) error
+	SendOtp(tenant, emailId, delivery string, policy db.TenantPolicy) error
 	SaveLoginInfo(tenant string, loginInfo *db.LoginModel) *db.LoginModel
 	GetLoginInfo(tenant, email string) *db.LoginModel
-	Verify(to, otp string) bool
+	Verify(tenant, to, otp string) bool
 }
 
 type EmailClient struct {
-	mongo odm.MongoClient
+	mongo     odm.MongoClient
+	nativeOtp *NativeOtpEngine
+	mailer    Mailer
 }
 
 func (c *EmailClient) IsValid(emailOrPhone string) bool {
@@ -49,11 +54,41 @@ func (c *EmailClient) GetLoginInfo(tenant, email string) *db.LoginModel {
 	return loginInfo
 }
 
-func (c *EmailClient) SendOtp(emailId string) error {
-	// TODO: Use twilio with send-grid to send otp to email.
+// generates otp using native otp engine and mails it using tenant template in user's preferred language.
+func (c *EmailClient) SendOtp(tenant, emailId, delivery string, policy db.TenantPolicy) error {
+	ctx := context.Background()
+
+	ttl := time.Duration(policy.OtpTtlSeconds) * time.Second
+	code, err := c.nativeOtp.Generate(tenant, emailId, policy.OtpLength, ttl)
+	if err != nil {
+		return fmt.Errorf("generating otp: %w", err)
+	}
+
+	language := ""
+	if loginInfo := <-db.FindOneByPhoneOrEmail(ctx, c.mongo, tenant, "", emailId); loginInfo != nil {
+		profile, err := async.Await(odm.CollectionOf[db.ProfileModel](c.mongo, tenant).FindOneByID(ctx, loginInfo.Id()))
+		if err == nil {
+			language = profile.PreferredLanguage
+		}
+	}
+
+	template := getOtpEmailTemplate(ctx, c.mongo, tenant, language)
+	mail, err := renderOtpEmail(template, emailId, otpEmailParams{
+		Otp:             code,
+		ValidForMinutes: int(ttl.Minutes()),
+		Tenant:          tenant,
+	})
+	if err != nil {
+		return fmt.Errorf("rendering otp email template %s: %w", template.Id(), err)
+	}
+
+	if err := c.mailer.Send(mail); err != nil {
+		return fmt.Errorf("sending otp email: %w", err)
+	}
 	return nil
 }
 
-func (c *EmailClient) Verify(to, otp string) bool {
-	return true
+// verifies otp against the challenge created by SendOtp.
+func (c *EmailClient) Verify(tenant, to, otp string) bool {
+	return c.nativeOtp.Verify(tenant, to, otp)
 }
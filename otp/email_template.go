@@ -0,0 +1,100 @@
+package otp
+
+import (
+	"bytes"
+	"context"
+	htmlTemplate "html/template"
+	"strings"
+	textTemplate "text/template"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+)
+
+const (
+	otpEmailTemplate = "otp"
+	defaultLanguage  = "english"
+)
+
+// used when tenant has not configured an otp template.
+var defaultOtpTemplate = db.EmailTemplateModel{
+	Language: defaultLanguage,
+	Name:     otpEmailTemplate,
+	Subject:  "Your verification code",
+	TextBody: "Your verification code is {{.Otp}}. It is valid for {{.ValidForMinutes}} minutes.",
+	HtmlBody: "<p>Your verification code is <b>{{.Otp}}</b>.</p><p>It is valid for {{.ValidForMinutes}} minutes.</p>",
+}
+
+type otpEmailParams struct {
+	Otp             string
+	ValidForMinutes int
+	Tenant          string
+}
+
+// gets tenant template for the language falling back to english and then to default template.
+func getOtpEmailTemplate(ctx context.Context, mongo odm.MongoClient, tenant, language string) db.EmailTemplateModel {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		language = defaultLanguage
+	}
+
+	languages := []string{language}
+	if language != defaultLanguage {
+		languages = append(languages, defaultLanguage)
+	}
+
+	for _, lang := range languages {
+		id := db.EmailTemplateModel{Language: lang, Name: otpEmailTemplate}.Id()
+		template, err := async.Await(odm.CollectionOf[db.EmailTemplateModel](mongo, tenant).FindOneByID(ctx, id))
+		if err == nil && template != nil {
+			return *template
+		}
+	}
+
+	return defaultOtpTemplate
+}
+
+func renderOtpEmail(template db.EmailTemplateModel, to string, params otpEmailParams) (*Mail, error) {
+	subject, err := renderText(template.Subject, params)
+	if err != nil {
+		return nil, err
+	}
+
+	textBody, err := renderText(template.TextBody, params)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlBody := ""
+	if template.HtmlBody != "" {
+		tmpl, err := htmlTemplate.New("html").Parse(template.HtmlBody)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, params); err != nil {
+			return nil, err
+		}
+		htmlBody = buf.String()
+	}
+
+	return &Mail{
+		To:       to,
+		Subject:  subject,
+		TextBody: textBody,
+		HtmlBody: htmlBody,
+	}, nil
+}
+
+func renderText(text string, params otpEmailParams) (string, error) {
+	tmpl, err := textTemplate.New("text").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,150 @@
+package otp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Kotlang/authGo/appconfig"
+)
+
+type Mail struct {
+	To       string
+	Subject  string
+	TextBody string
+	HtmlBody string
+}
+
+type Mailer interface {
+	Send(mail *Mail) error
+}
+
+// SmtpMailer sends mails through a plain SMTP server upgraded with STARTTLS.
+type SmtpMailer struct {
+	host       string
+	port       int
+	username   string
+	password   string
+	from       mail.Address
+	allowPlain bool
+}
+
+func ProvideSmtpMailer(ccfg *appconfig.AppConfig) *SmtpMailer {
+	port := ccfg.SmtpPort
+	if port == 0 {
+		port = 587
+	}
+
+	return &SmtpMailer{
+		host:       ccfg.SmtpHost,
+		port:       port,
+		username:   ccfg.SmtpUsername,
+		password:   os.Getenv("SMTP-PASSWORD"),
+		from:       mail.Address{Name: ccfg.SmtpFromName, Address: ccfg.SmtpFromAddress},
+		allowPlain: ccfg.SmtpAllowPlain,
+	}
+}
+
+func (m *SmtpMailer) Send(msg *Mail) error {
+	if m.host == "" || m.from.Address == "" {
+		return errors.New("smtp_host or smtp_from_address is not set")
+	}
+
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("connecting to smtp server %s: %w", addr, err)
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("starting smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	} else if !m.allowPlain {
+		return errors.New("smtp server does not support STARTTLS")
+	}
+
+	if m.username != "" {
+		auth := smtp.PlainAuth("", m.username, m.password, m.host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("smtp RCPT TO: %w", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+
+	body, err := m.buildMessage(msg)
+	if err != nil {
+		writer.Close()
+		return err
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		writer.Close()
+		return fmt.Errorf("writing mail body: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// builds a multipart/alternative message with text and html parts.
+func (m *SmtpMailer) buildMessage(msg *Mail) ([]byte, error) {
+	boundaryBytes := make([]byte, 12)
+	if _, err := rand.Read(boundaryBytes); err != nil {
+		return nil, err
+	}
+	boundary := hex.EncodeToString(boundaryBytes)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	buf.WriteString(msg.TextBody)
+	buf.WriteString("\r\n")
+
+	if msg.HtmlBody != "" {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		buf.WriteString("Content-Type: text/html; charset=\"utf-8\"\r\n\r\n")
+		buf.WriteString(msg.HtmlBody)
+		buf.WriteString("\r\n")
+	}
+
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,217 @@
+package otp
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/ratelimit"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSmtp is a local SMTP stand-in speaking just enough of the protocol for net/smtp.
+type fakeSmtp struct {
+	listener   net.Listener
+	rejectRcpt bool
+	messages   chan string
+}
+
+func startFakeSmtp(t *testing.T, rejectRcpt bool) *fakeSmtp {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSmtp{listener: listener, rejectRcpt: rejectRcpt, messages: make(chan string, 1)}
+	go server.serve()
+	return server
+}
+
+func (s *fakeSmtp) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSmtp) handle(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 fake smtp ready")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command, _, _ := strings.Cut(strings.ToUpper(line), " ")
+		switch command {
+		case "EHLO", "HELO":
+			// no extensions, so no STARTTLS.
+			text.PrintfLine("250 fake smtp")
+		case "MAIL":
+			text.PrintfLine("250 ok")
+		case "RCPT":
+			if s.rejectRcpt {
+				text.PrintfLine("550 mailbox unavailable")
+			} else {
+				text.PrintfLine("250 ok")
+			}
+		case "DATA":
+			text.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.messages <- string(data)
+			text.PrintfLine("250 queued")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeSmtp) mailer(t *testing.T, allowPlain bool) *SmtpMailer {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	return &SmtpMailer{
+		host:       host,
+		port:       portNumber,
+		from:       mail.Address{Name: "Kotlang", Address: "no-reply@kotlang.dev"},
+		allowPlain: allowPlain,
+	}
+}
+
+func TestSmtpMailerSendsThroughLocalServer(t *testing.T) {
+	server := startFakeSmtp(t, false)
+
+	err := server.mailer(t, true).Send(&Mail{
+		To:       "user@example.com",
+		Subject:  "Your OTP",
+		TextBody: "Your otp is 123456",
+		HtmlBody: "<b>123456</b>",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	select {
+	case message := <-server.messages:
+		for _, want := range []string{"To: user@example.com", "Subject: Your OTP", "Your otp is 123456", "<b>123456</b>"} {
+			if !strings.Contains(message, want) {
+				t.Errorf("message does not contain %q:\n%s", want, message)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not receive the message")
+	}
+}
+
+func TestSmtpMailerReturnsRejectedRecipient(t *testing.T) {
+	server := startFakeSmtp(t, true)
+
+	err := server.mailer(t, true).Send(&Mail{To: "user@example.com", Subject: "Your OTP", TextBody: "123456"})
+	if err == nil || !strings.Contains(err.Error(), "RCPT TO") {
+		t.Fatalf("Send() error = %v, want RCPT TO error", err)
+	}
+}
+
+func TestSmtpMailerRequiresStartTls(t *testing.T) {
+	server := startFakeSmtp(t, false)
+
+	err := server.mailer(t, false).Send(&Mail{To: "user@example.com", Subject: "Your OTP", TextBody: "123456"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Send() error = %v, want STARTTLS error", err)
+	}
+}
+
+// unreachableMongo returns a client whose operations fail fast, so lookups fall back to defaults.
+func unreachableMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("creating mongo client: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return client
+}
+
+// recordingEmailClient is an EmailClient recording whether login info was saved.
+type recordingEmailClient struct {
+	*EmailClient
+	saved bool
+}
+
+func (c *recordingEmailClient) SaveLoginInfo(tenant string, loginInfo *db.LoginModel) *db.LoginModel {
+	c.saved = true
+	return loginInfo
+}
+
+// countingQuota allows every otp and counts the ones not refunded.
+type countingQuota struct {
+	used int64
+}
+
+func (q *countingQuota) Allow(ctx context.Context, tenant string, limit ratelimit.Limit, key string) (bool, time.Duration, error) {
+	q.used++
+	return true, 0, nil
+}
+
+func (q *countingQuota) Remaining(ctx context.Context, tenant string, limit ratelimit.Limit, key string) (int64, error) {
+	return limit.Max - q.used, nil
+}
+
+func (q *countingQuota) Refund(ctx context.Context, tenant string, limit ratelimit.Limit, key string) error {
+	q.used--
+	return nil
+}
+
+func TestOtpClientReturnsMailerErrors(t *testing.T) {
+	server := startFakeSmtp(t, true)
+	mongoClient := unreachableMongo(t)
+	channel := &recordingEmailClient{EmailClient: &EmailClient{
+		mongo: mongoClient,
+		nativeOtp: &NativeOtpEngine{
+			challenges:  &memoryChallengeStore{challenges: map[string]db.OtpChallengeModel{}},
+			length:      defaultOtpLength,
+			ttl:         defaultOtpTtl,
+			maxAttempts: defaultOtpMaxAttempts,
+		},
+		mailer: server.mailer(t, true),
+	}}
+	quota := &countingQuota{}
+	client := &OtpClient{channels: []Channel{channel}, limiter: quota}
+
+	err := client.SendOtp("tenant", "user@example.com", "", db.TenantPolicy{}.WithDefaults())
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("SendOtp() error = %v, want Unavailable", err)
+	}
+	if channel.saved {
+		t.Error("login info saved although otp was not sent")
+	}
+	if quota.used != 0 {
+		t.Errorf("otp quota used = %d although otp was not sent, want 0", quota.used)
+	}
+}
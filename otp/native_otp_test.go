@@ -0,0 +1,45 @@
+package otp
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+)
+
+func TestConcurrentNativeVerifyAcceptsCodeOnce(t *testing.T) {
+	store := &memoryChallengeStore{challenges: map[string]db.OtpChallengeModel{}}
+	// enough attempts for every call so that only claiming the challenge limits successes.
+	engine := &NativeOtpEngine{challenges: store, length: defaultOtpLength, ttl: time.Minute, maxAttempts: 50}
+
+	code, err := engine.Generate("tenant", testPhone, 0, 0)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	var verified atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if engine.Verify("tenant", testPhone, code) {
+				verified.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if got := verified.Load(); got != 1 {
+		t.Fatalf("verified %d times, want 1", got)
+	}
+
+	if engine.Verify("tenant", testPhone, code) {
+		t.Error("used code accepted again")
+	}
+}
@@ -1,56 +1,144 @@
 package otp
 
 import (
+	"context"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/Kotlang/authGo/apierror"
+	"github.com/Kotlang/authGo/appconfig"
 	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/ratelimit"
+	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type Channel interface {
 	IsValid(to string) bool
-	SendOtp(to string) error
+	// delivery is one of the Delivery* mediums, empty for channels with a single medium.
+	SendOtp(tenant, to, delivery string, policy db.TenantPolicy) error
 	GetLoginInfo(tenant, to string) *db.LoginModel
 	SaveLoginInfo(tenant string, loginInfo *db.LoginModel) *db.LoginModel
-	Verify(to, otp string) bool
+	Verify(tenant, to, otp string) bool
 }
 
 type OtpClientInterface interface {
-	SendOtp(tenant, to string) error
+	// delivery is the requested medium for phone otps, empty for tenant default.
+	// policy decides otp length, validity, resend interval and daily cap.
+	SendOtp(tenant, to, delivery string, policy db.TenantPolicy) error
 	GetLoginInfo(tenant, to string) *db.LoginModel
 	ValidateOtp(tenant, to, otp string) bool
 }
 
+// otpQuota counts otps sent to an email/phone, implemented by *ratelimit.Limiter.
+type otpQuota interface {
+	Allow(ctx context.Context, tenant string, limit ratelimit.Limit, key string) (bool, time.Duration, error)
+	Remaining(ctx context.Context, tenant string, limit ratelimit.Limit, key string) (int64, error)
+	Refund(ctx context.Context, tenant string, limit ratelimit.Limit, key string) error
+}
+
 type OtpClient struct {
-	mongo    odm.MongoClient
-	channels []Channel
+	mongo            odm.MongoClient
+	channels         []Channel
+	escalationWindow time.Duration
+	limiter          otpQuota
 }
 
-func ProvideOtpClient(mongo odm.MongoClient) OtpClientInterface {
+// Emails are verified natively. Phone otps are sent and verified by Twilio Verify.
+func ProvideOtpClient(mongo odm.MongoClient, ccfg *appconfig.AppConfig) OtpClientInterface {
+	nativeOtp := ProvideNativeOtpEngine(mongo)
+	mailer := ProvideSmtpMailer(ccfg)
+
 	return &OtpClient{
-		mongo:    mongo,
-		channels: []Channel{&EmailClient{mongo: mongo}, &PhoneClient{mongo: mongo}},
+		mongo: mongo,
+		channels: []Channel{
+			&EmailClient{mongo: mongo, nativeOtp: nativeOtp, mailer: mailer},
+			ProvidePhoneClient(mongo),
+		},
+		escalationWindow: getEscalationWindow(ccfg),
+		limiter:          ratelimit.ProvideLimiter(mongo),
 	}
 }
 
-func (c *OtpClient) SendOtp(tenant, to string) error {
+// All otps are generated and verified natively. Phone otps are delivered as plain sms.
+func ProvideNativeOtpClient(mongo odm.MongoClient, ccfg *appconfig.AppConfig) OtpClientInterface {
+	nativeOtp := ProvideNativeOtpEngine(mongo)
+	mailer := ProvideSmtpMailer(ccfg)
+	phoneClient := ProvidePhoneClient(mongo)
+
+	return &OtpClient{
+		mongo: mongo,
+		channels: []Channel{
+			&EmailClient{mongo: mongo, nativeOtp: nativeOtp, mailer: mailer},
+			&SmsClient{PhoneClient: phoneClient, nativeOtp: nativeOtp},
+		},
+		escalationWindow: getEscalationWindow(ccfg),
+		limiter:          ratelimit.ProvideLimiter(mongo),
+	}
+}
+
+func getEscalationWindow(ccfg *appconfig.AppConfig) time.Duration {
+	if ccfg.OtpEscalationMinutes <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(ccfg.OtpEscalationMinutes) * time.Minute
+}
+
+func (c *OtpClient) SendOtp(tenant, to, delivery string, policy db.TenantPolicy) error {
+	// minimum gap between two otps sent to the same email/phone.
+	resendCooldown := time.Duration(policy.ResendIntervalSeconds) * time.Second
+	dailyLimit := ratelimit.OtpSendsPerDay
+	dailyLimit.Max = int64(policy.DailyOtpCap)
+
 	for _, channel := range c.channels {
 		if channel.IsValid(to) {
 			now := time.Now().Unix()
 			// get login info from db or default info.
 			loginInfo := channel.GetLoginInfo(tenant, to)
-			if loginInfo.CreatedOn != 0 && (now-loginInfo.LastOtpSentTime) < 60 {
-				return status.Error(codes.PermissionDenied, "Exceeded threshold of OTPs in a minute.")
+			sinceLastOtp := time.Duration(now-loginInfo.LastOtpSentTime) * time.Second
+			if loginInfo.CreatedOn != 0 && sinceLastOtp < resendCooldown {
+				return c.resendCooldownError(tenant, to, dailyLimit, resendCooldown-sinceLastOtp, channelName(channel, loginInfo.LastOtpDelivery))
+			}
+
+			// pick delivery medium before updating last sent time as it decides escalation.
+			if multiDelivery, ok := channel.(MultiDeliveryChannel); ok {
+				selected, err := selectDelivery(multiDelivery.AllowedDeliveries(tenant), delivery, loginInfo, c.escalationWindow)
+				if err != nil {
+					return err
+				}
+				delivery = selected
+			} else {
+				delivery = ""
+			}
+
+			allowed, retryAfter, err := c.limiter.Allow(context.Background(), tenant, dailyLimit, to)
+			if err != nil {
+				logger.Error("Error checking otp quota", zap.String("tenant", tenant), zap.Error(err))
+				return status.Error(codes.Unavailable, "Failed sending otp")
+			}
+			if !allowed {
+				return apierror.New(codes.ResourceExhausted, apierror.ReasonOtpDailyQuota, "Exceeded daily quota of OTPs.", retryAfter, map[string]string{
+					"remainingDailyQuota": "0",
+					"channel":             channelName(channel, delivery),
+				})
 			}
 
 			loginInfo.LastOtpSentTime = now
+			loginInfo.LastOtpDelivery = delivery
 
 			// send otp through the channel.
-			err := channel.SendOtp(to)
+			err = channel.SendOtp(tenant, to, delivery, policy)
 			if err != nil {
-				return status.Error(codes.Internal, "Failed sending otp")
+				logger.Error("Failed sending otp", zap.String("tenant", tenant), zap.Error(err))
+				// only delivered otps count towards the daily quota.
+				if err := c.limiter.Refund(context.Background(), tenant, dailyLimit, to); err != nil {
+					logger.Error("Error refunding otp quota", zap.String("tenant", tenant), zap.Error(err))
+				}
+				return status.Error(codes.Unavailable, "Failed sending otp")
 			}
 
 			// if the user is new populate the UserId field to avoid email and phone clients generating two different ids
@@ -66,6 +154,30 @@ func (c *OtpClient) SendOtp(tenant, to string) error {
 	return status.Error(codes.InvalidArgument, "Incorrect email or phone")
 }
 
+func (c *OtpClient) resendCooldownError(tenant, to string, dailyLimit ratelimit.Limit, retryAfter time.Duration, channel string) error {
+	metadata := map[string]string{"channel": channel}
+	remaining, err := c.limiter.Remaining(context.Background(), tenant, dailyLimit, to)
+	if err != nil {
+		logger.Error("Error fetching otp quota", zap.String("tenant", tenant), zap.Error(err))
+	} else {
+		metadata["remainingDailyQuota"] = strconv.FormatInt(remaining, 10)
+	}
+
+	return apierror.New(codes.ResourceExhausted, apierror.ReasonOtpResendCooldown,
+		fmt.Sprintf("Wait %d seconds before requesting another OTP.", int(retryAfter.Seconds())), retryAfter, metadata)
+}
+
+// medium reported to clients in error details.
+func channelName(channel Channel, delivery string) string {
+	if delivery != "" {
+		return delivery
+	}
+	if _, ok := channel.(*EmailClient); ok {
+		return "email"
+	}
+	return DeliverySms
+}
+
 func (c *OtpClient) GetLoginInfo(tenant, to string) *db.LoginModel {
 	for _, channel := range c.channels {
 		if channel.IsValid(to) {
@@ -79,10 +191,9 @@ func (c *OtpClient) GetLoginInfo(tenant, to string) *db.LoginModel {
 func (c *OtpClient) ValidateOtp(tenant, to, otp string) bool {
 	for _, channel := range c.channels {
 		if channel.IsValid(to) {
-			isValid := channel.Verify(to, otp)
+			isValid := channel.Verify(tenant, to, otp)
 			if isValid {
 				loginInfo := channel.GetLoginInfo(tenant, to)
-				loginInfo.Otp = otp
 				loginInfo.OtpAuthenticatedTime = time.Now().Unix()
 				channel.SaveLoginInfo(tenant, loginInfo)
 			}
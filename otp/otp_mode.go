@@ -0,0 +1,44 @@
+package otp
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Kotlang/authGo/appconfig"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+)
+
+const (
+	// accepts every otp. Never use in production.
+	OtpModeDev = "dev"
+	// phone otps through Twilio Verify, email otps natively.
+	OtpModeTwilio = "twilio"
+	// all otps natively, phone otps delivered as sms.
+	OtpModeNative = "native"
+)
+
+// values of ENV env variable which are explicitly non-production.
+var nonProductionEnvs = []string{"dev", "local", "test"}
+
+// ProvideOtpClientForMode returns the otp client selected by otp_mode config.
+// Dev mode is refused unless ENV marks the environment as non-production.
+func ProvideOtpClientForMode(mongo odm.MongoClient, ccfg *appconfig.AppConfig) (OtpClientInterface, error) {
+	switch strings.ToLower(strings.TrimSpace(ccfg.OtpMode)) {
+	case OtpModeDev:
+		env := strings.ToLower(os.Getenv("ENV"))
+		if !slices.Contains(nonProductionEnvs, env) {
+			return nil, errors.New("otp_mode dev accepts any otp and is allowed only when ENV is one of " + strings.Join(nonProductionEnvs, ", "))
+		}
+		return &DevOtpClient{}, nil
+	case OtpModeTwilio:
+		return ProvideOtpClient(mongo, ccfg), nil
+	case OtpModeNative:
+		return ProvideNativeOtpClient(mongo, ccfg), nil
+	case "":
+		return nil, errors.New("otp_mode is not set")
+	default:
+		return nil, errors.New("unknown otp_mode " + ccfg.OtpMode)
+	}
+}
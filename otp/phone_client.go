@@ -2,41 +2,45 @@ package otp
 
 import (
 	"context"
-	"os"
+	"errors"
+	"fmt"
 	"strings"
-	"unicode"
+	"time"
 
 	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/phonenumber"
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
-	"github.com/twilio/twilio-go"
 	openapi "github.com/twilio/twilio-go/rest/verify/v2"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 )
 
 type PhoneClientInterface interface {
 	IsValid(emailOrPhone string) bool
-	SendOtp(phoneNumber string) error
+	SendOtp(tenant, phoneNumber, delivery string, policy db.TenantPolicy) error
 	SaveLoginInfo(tenant string, loginInfo *db.LoginModel) *db.LoginModel
 	GetLoginInfo(tenant, phone string) *db.LoginModel
-	Verify(to, otp string) bool
+	Verify(tenant, to, otp string) bool
 }
 
 type PhoneClient struct {
-	mongo odm.MongoClient
+	mongo      odm.MongoClient
+	twilio     *TwilioClientCache
+	challenges challengeStore
 }
 
-func isAllDigit(s string) bool {
-	for _, c := range s {
-		if !unicode.IsDigit(c) {
-			return false
-		}
+func ProvidePhoneClient(mongo odm.MongoClient) *PhoneClient {
+	return &PhoneClient{
+		mongo:      mongo,
+		twilio:     ProvideTwilioClientCache(mongo),
+		challenges: mongoChallengeStore{mongo: mongo},
 	}
-	return true
 }
 
+// phone numbers are expected to be normalized to E.164 by the caller.
 func (c *PhoneClient) IsValid(emailOrPhone string) bool {
-	return len(emailOrPhone) == 10 && isAllDigit(emailOrPhone)
+	return phonenumber.IsE164(emailOrPhone)
 }
 
 func (c *PhoneClient) SaveLoginInfo(tenant string, loginInfo *db.LoginModel) *db.LoginModel {
@@ -62,40 +66,108 @@ func (c *PhoneClient) GetLoginInfo(tenant, phone string) *db.LoginModel {
 			UserType: "member",
 		}
 	}
+
+	// migrates records holding national number to E.164 when saved.
+	loginInfo.Phone = phone
 	return loginInfo
 }
 
-// sends otp to phone number using twilio.
-func (c *PhoneClient) SendOtp(phoneNumber string) error {
-	accountSid := os.Getenv("TWILIO-ACCOUNT-SID")
-	authToken := os.Getenv("TWILIO-AUTH-TOKEN")
-	client := twilio.NewRestClient(accountSid, authToken)
+// twilio verify channel for each delivery medium.
+var verifyChannels = map[string]string{
+	DeliverySms:      "sms",
+	DeliveryWhatsapp: "whatsapp",
+	DeliveryVoice:    "call",
+}
 
-	channel := "sms"
-	phoneNumber = "+91" + phoneNumber
+// deliveries allowed in tenant's twilio config.
+func (c *PhoneClient) AllowedDeliveries(tenant string) []string {
+	twilioTenant, err := c.twilio.Get(tenant)
+	if err != nil || len(twilioTenant.config.AllowedDeliveries) == 0 {
+		return defaultDeliveries
+	}
+	return twilioTenant.config.AllowedDeliveries
+}
+
+// sends otp to phone number using tenant's twilio verify service.
+// Pending verification of the number is cancelled so that only the latest code works.
+// Code length and validity are set on the verify service, not by the tenant policy.
+func (c *PhoneClient) SendOtp(tenant, phoneNumber, delivery string, policy db.TenantPolicy) error {
+	ctx := context.Background()
 
-	res, err := client.VerifyV2.CreateVerification("VAfa78c49eba6901f198481a166a704019", &openapi.CreateVerificationParams{
+	twilioTenant, err := c.twilio.Get(tenant)
+	if err != nil {
+		return err
+	}
+	if twilioTenant.config.VerifyServiceSid == "" {
+		return errors.New("twilio verify service is not configured for tenant " + tenant)
+	}
+
+	pending, err := c.challenges.Get(ctx, tenant, phoneNumber)
+	if err == nil && pending.ProviderSid != "" {
+		_, err := twilioTenant.client.VerifyV2.UpdateVerification(twilioTenant.config.VerifyServiceSid, pending.ProviderSid,
+			(&openapi.UpdateVerificationParams{}).SetStatus("canceled"))
+		if err != nil {
+			// verification may have already expired or been approved.
+			logger.Info("Failed cancelling pending verification", zap.String("sid", pending.ProviderSid), zap.Error(err))
+		}
+	}
+
+	channel, ok := verifyChannels[delivery]
+	if !ok {
+		channel = verifyChannels[DeliverySms]
+	}
+
+	params := &openapi.CreateVerificationParams{
 		Channel: &channel,
 		To:      &phoneNumber,
 		// CustomCode: &otp,
-	})
+	}
+	if twilioTenant.config.FriendlyName != "" {
+		params.CustomFriendlyName = &twilioTenant.config.FriendlyName
+	}
+	if twilioTenant.config.Locale != "" {
+		params.Locale = &twilioTenant.config.Locale
+	}
+
+	res, err := twilioTenant.client.VerifyV2.CreateVerification(twilioTenant.config.VerifyServiceSid, params)
 
 	if err != nil {
 		logger.Error("Failed sending otp", zap.Error(err))
 		return err
 	}
-	logger.Info("Sending otp status", zap.String("status", *res.Status))
+	logger.Info("Sending otp status", zap.String("status", *res.Status), zap.String("channel", channel))
+
+	// verifications without a tracked challenge are refused, so the send fails with it.
+	if res.Sid == nil {
+		return errors.New("twilio returned no verification sid")
+	}
+	challenge := db.OtpChallengeModel{
+		Identifier:  phoneNumber,
+		ProviderSid: *res.Sid,
+		ExpiresOn:   time.Now().Add(defaultOtpTtl).Unix(),
+	}
+	if err := c.challenges.Save(ctx, tenant, challenge); err != nil {
+		return fmt.Errorf("saving otp challenge: %w", err)
+	}
 	return nil
 }
 
 // verifies otp and returns true if otp is valid.
-func (c *PhoneClient) Verify(to, otp string) bool {
-	accountSid := os.Getenv("TWILIO-ACCOUNT-SID")
-	authToken := os.Getenv("TWILIO-AUTH-TOKEN")
-	client := twilio.NewRestClient(accountSid, authToken)
+// An approved verification is consumed so that concurrent checks of the same code cannot both succeed.
+func (c *PhoneClient) Verify(tenant, to, otp string) bool {
+	ctx := context.Background()
 
-	to = "+91" + to
-	verificationCheck, err := client.VerifyV2.CreateVerificationCheck("VAfa78c49eba6901f198481a166a704019", &openapi.CreateVerificationCheckParams{
+	twilioTenant, err := c.twilio.Get(tenant)
+	if err != nil {
+		logger.Error("Failed getting twilio client", zap.String("tenant", tenant), zap.Error(err))
+		return false
+	}
+	if twilioTenant.config.VerifyServiceSid == "" {
+		logger.Error("Twilio verify service is not configured", zap.String("tenant", tenant))
+		return false
+	}
+
+	verificationCheck, err := twilioTenant.client.VerifyV2.CreateVerificationCheck(twilioTenant.config.VerifyServiceSid, &openapi.CreateVerificationCheckParams{
 		Code: &otp,
 		To:   &to,
 	})
@@ -104,5 +176,39 @@ func (c *PhoneClient) Verify(to, otp string) bool {
 		logger.Error("Sdk validation failed.", zap.Error(err))
 		return false
 	}
-	return *verificationCheck.Valid
+	if verificationCheck.Valid == nil || !*verificationCheck.Valid {
+		return false
+	}
+
+	verificationSid := ""
+	if verificationCheck.Sid != nil {
+		verificationSid = *verificationCheck.Sid
+	}
+	return consumeVerification(ctx, c.challenges, tenant, to, verificationSid)
+}
+
+// consumeVerification claims the challenge of a verification approved by twilio.
+// Only one of concurrent checks of the same verification succeeds.
+// Verifications without a tracked challenge are refused.
+func consumeVerification(ctx context.Context, challenges challengeStore, tenant, to, verificationSid string) bool {
+	challenge, err := challenges.Get(ctx, tenant, to)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			logger.Error("Failed getting otp challenge", zap.Error(err))
+		}
+		return false
+	}
+	if challenge.ProviderSid == "" || challenge.ConsumedOn != 0 {
+		return false
+	}
+	if verificationSid != "" && verificationSid != challenge.ProviderSid {
+		return false
+	}
+
+	claimed, err := challenges.Claim(ctx, tenant, *challenge)
+	if err != nil {
+		logger.Error("Failed consuming otp challenge", zap.Error(err))
+		return false
+	}
+	return claimed
 }
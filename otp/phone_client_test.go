@@ -0,0 +1,148 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryChallengeStore counts attempts and claims challenges atomically like the conditional updates in mongo.
+type memoryChallengeStore struct {
+	mu         sync.Mutex
+	challenges map[string]db.OtpChallengeModel
+	err        error
+}
+
+func (s *memoryChallengeStore) Get(ctx context.Context, tenant, identifier string) (*db.OtpChallengeModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	challenge, ok := s.challenges[identifier]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &challenge, nil
+}
+
+func (s *memoryChallengeStore) Save(ctx context.Context, tenant string, challenge db.OtpChallengeModel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.challenges[challenge.Identifier] = challenge
+	return nil
+}
+
+func (s *memoryChallengeStore) Delete(ctx context.Context, tenant, identifier string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.challenges, identifier)
+	return nil
+}
+
+func (s *memoryChallengeStore) ConsumeAttempt(ctx context.Context, tenant, identifier string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.challenges[identifier]
+	if !ok || stored.AttemptsRemaining <= 0 {
+		return false, nil
+	}
+	stored.AttemptsRemaining--
+	s.challenges[identifier] = stored
+	return true, nil
+}
+
+func (s *memoryChallengeStore) Claim(ctx context.Context, tenant string, challenge db.OtpChallengeModel) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.challenges[challenge.Identifier]
+	if !ok {
+		return false, nil
+	}
+	if challenge.ProviderSid == "" {
+		if stored.CodeHash != challenge.CodeHash {
+			return false, nil
+		}
+		delete(s.challenges, challenge.Identifier)
+		return true, nil
+	}
+	if stored.ProviderSid != challenge.ProviderSid || stored.ConsumedOn != 0 {
+		return false, nil
+	}
+	stored.ConsumedOn = time.Now().Unix()
+	s.challenges[challenge.Identifier] = stored
+	return true, nil
+}
+
+const testPhone = "+919876543210"
+
+func TestConcurrentVerifyConsumesVerificationOnce(t *testing.T) {
+	store := &memoryChallengeStore{challenges: map[string]db.OtpChallengeModel{
+		testPhone: {Identifier: testPhone, ProviderSid: "VE123"},
+	}}
+
+	var verified atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if consumeVerification(context.Background(), store, "tenant", testPhone, "VE123") {
+				verified.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if got := verified.Load(); got != 1 {
+		t.Fatalf("verified %d times, want 1", got)
+	}
+
+	// a replay after the verification was consumed is refused.
+	if consumeVerification(context.Background(), store, "tenant", testPhone, "VE123") {
+		t.Error("consumed verification accepted again")
+	}
+}
+
+func TestVerifyRefusesOtherVerification(t *testing.T) {
+	store := &memoryChallengeStore{challenges: map[string]db.OtpChallengeModel{
+		testPhone: {Identifier: testPhone, ProviderSid: "VE123"},
+	}}
+
+	if consumeVerification(context.Background(), store, "tenant", testPhone, "VE456") {
+		t.Error("verification of a replaced challenge accepted")
+	}
+}
+
+func TestVerifyFailsClosedOnStoreError(t *testing.T) {
+	store := &memoryChallengeStore{err: errors.New("connection reset")}
+
+	if consumeVerification(context.Background(), store, "tenant", testPhone, "VE123") {
+		t.Error("verification accepted although the challenge could not be read")
+	}
+}
+
+func TestVerifyRefusesUntrackedVerification(t *testing.T) {
+	store := &memoryChallengeStore{challenges: map[string]db.OtpChallengeModel{}}
+
+	if consumeVerification(context.Background(), store, "tenant", testPhone, "VE123") {
+		t.Error("verification without a challenge accepted")
+	}
+}
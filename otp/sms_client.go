@@ -0,0 +1,98 @@
+package otp
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	openapi "github.com/twilio/twilio-go/rest/api/v2010"
+	"go.uber.org/zap"
+)
+
+// SmsClient delivers natively generated otps through Twilio messaging or a voice call.
+// Unlike PhoneClient, verification does not depend on Twilio Verify.
+type SmsClient struct {
+	*PhoneClient
+	nativeOtp *NativeOtpEngine
+}
+
+func (c *SmsClient) SendOtp(tenant, phoneNumber, delivery string, policy db.TenantPolicy) error {
+	twilioTenant, err := c.twilio.Get(tenant)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Duration(policy.OtpTtlSeconds) * time.Second
+	code, err := c.nativeOtp.Generate(tenant, phoneNumber, policy.OtpLength, ttl)
+	if err != nil {
+		return fmt.Errorf("generating otp: %w", err)
+	}
+
+	sender := twilioTenant.config.FriendlyName
+	if sender == "" {
+		sender = tenant
+	}
+
+	switch delivery {
+	case DeliveryVoice:
+		return c.call(twilioTenant, phoneNumber, code, sender)
+	case DeliveryWhatsapp:
+		return c.message(twilioTenant, "whatsapp:"+phoneNumber, code, sender, ttl)
+	default:
+		return c.message(twilioTenant, phoneNumber, code, sender, ttl)
+	}
+}
+
+func (c *SmsClient) message(twilioTenant *twilioTenant, to, code, sender string, ttl time.Duration) error {
+	if twilioTenant.config.MessagingServiceSid == "" {
+		return errors.New("twilio messaging service is not configured")
+	}
+
+	body := fmt.Sprintf("%s is your %s verification code. It is valid for %d minutes.", code, sender, int(ttl.Minutes()))
+	res, err := twilioTenant.client.ApiV2010.CreateMessage(&openapi.CreateMessageParams{
+		MessagingServiceSid: &twilioTenant.config.MessagingServiceSid,
+		To:                  &to,
+		Body:                &body,
+	})
+	if err != nil {
+		return fmt.Errorf("sending otp message: %w", err)
+	}
+
+	if res.Status != nil {
+		logger.Info("Sending otp status", zap.String("status", *res.Status))
+	}
+	return nil
+}
+
+// reads out the otp digit by digit twice.
+func (c *SmsClient) call(twilioTenant *twilioTenant, to, code, sender string) error {
+	if twilioTenant.config.VoiceCallerId == "" {
+		return errors.New("twilio voice caller id is not configured")
+	}
+
+	digits := strings.Join(strings.Split(code, ""), ", ")
+	twiml := fmt.Sprintf(`<Response><Say>Your %s verification code is %s.</Say><Pause length="1"/><Say>Again, your code is %s.</Say></Response>`,
+		html.EscapeString(sender), digits, digits)
+
+	res, err := twilioTenant.client.ApiV2010.CreateCall(&openapi.CreateCallParams{
+		From:  &twilioTenant.config.VoiceCallerId,
+		To:    &to,
+		Twiml: &twiml,
+	})
+	if err != nil {
+		return fmt.Errorf("calling with otp: %w", err)
+	}
+
+	if res.Status != nil {
+		logger.Info("Otp call status", zap.String("status", *res.Status))
+	}
+	return nil
+}
+
+func (c *SmsClient) Verify(tenant, to, otp string) bool {
+	return c.nativeOtp.Verify(tenant, to, otp)
+}
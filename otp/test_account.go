@@ -0,0 +1,75 @@
+package otp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestAccounts manages per tenant test identities that log in with a static code.
+// No otp is ever sent to a test account.
+type TestAccounts struct {
+	mongo odm.MongoClient
+}
+
+func ProvideTestAccounts(mongo odm.MongoClient) *TestAccounts {
+	return &TestAccounts{mongo: mongo}
+}
+
+// FindActive returns the unexpired test account of the email/phone, nil if there is none.
+func (t *TestAccounts) FindActive(ctx context.Context, tenant, emailOrPhone string) *db.TestAccountModel {
+	account, err := async.Await(odm.CollectionOf[db.TestAccountModel](t.mongo, tenant).FindOneByID(ctx, emailOrPhone))
+	if err != nil || time.Now().Unix() > account.ExpiresOn {
+		return nil
+	}
+	return account
+}
+
+// Verify compares the code with the account's static code in constant time.
+func (t *TestAccounts) Verify(account *db.TestAccountModel, code string) bool {
+	salt, err := hex.DecodeString(account.Salt)
+	if err != nil {
+		return false
+	}
+
+	expected, err := hex.DecodeString(account.CodeHash)
+	if err != nil {
+		return false
+	}
+
+	actual, _ := hex.DecodeString(hashOtp(salt, code))
+	return hmac.Equal(expected, actual)
+}
+
+// Save creates or replaces the test account. Only a salted hash of the code is stored.
+func (t *TestAccounts) Save(ctx context.Context, tenant string, account db.TestAccountModel, code string) (*db.TestAccountModel, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	account.Salt = hex.EncodeToString(salt)
+	account.CodeHash = hashOtp(salt, code)
+
+	_, err := async.Await(odm.CollectionOf[db.TestAccountModel](t.mongo, tenant).Save(ctx, account))
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (t *TestAccounts) List(ctx context.Context, tenant string) ([]db.TestAccountModel, error) {
+	return async.Await(odm.CollectionOf[db.TestAccountModel](t.mongo, tenant).Find(ctx, bson.M{}, bson.D{{Key: "createdOn", Value: -1}}, 0, 0))
+}
+
+func (t *TestAccounts) Delete(ctx context.Context, tenant, emailOrPhone string) error {
+	_, err := async.Await(odm.CollectionOf[db.TestAccountModel](t.mongo, tenant).DeleteByID(ctx, emailOrPhone))
+	return err
+}
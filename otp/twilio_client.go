@@ -0,0 +1,109 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/twilio/twilio-go"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// tenant config is reloaded after this duration so that db updates are picked up.
+const twilioConfigTtl = 5 * time.Minute
+
+type twilioTenant struct {
+	config    db.TwilioConfigModel
+	authToken string
+	client    *twilio.RestClient
+	loadedAt  time.Time
+}
+
+// TwilioClientCache resolves Twilio Verify config per tenant and caches a rest client for each.
+type TwilioClientCache struct {
+	mongo   odm.MongoClient
+	lock    sync.Mutex
+	tenants map[string]*twilioTenant
+}
+
+func ProvideTwilioClientCache(mongo odm.MongoClient) *TwilioClientCache {
+	return &TwilioClientCache{
+		mongo:   mongo,
+		tenants: map[string]*twilioTenant{},
+	}
+}
+
+func (c *TwilioClientCache) Get(tenant string) (*twilioTenant, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cached, ok := c.tenants[tenant]
+	if ok && time.Since(cached.loadedAt) < twilioConfigTtl {
+		return cached, nil
+	}
+
+	config, err := loadTwilioConfig(tenant, c.mongo)
+	if err != nil {
+		return nil, err
+	}
+
+	authToken := getTwilioAuthToken(config.AccountSid)
+	if authToken == "" {
+		return nil, errors.New("twilio auth token is not set for account " + config.AccountSid)
+	}
+
+	// reuse client if credentials have not changed.
+	var client *twilio.RestClient
+	if ok && cached.config.AccountSid == config.AccountSid && cached.authToken == authToken {
+		client = cached.client
+	} else {
+		client = twilio.NewRestClient(config.AccountSid, authToken)
+	}
+
+	c.tenants[tenant] = &twilioTenant{
+		config:    *config,
+		authToken: authToken,
+		client:    client,
+		loadedAt:  time.Now(),
+	}
+	return c.tenants[tenant], nil
+}
+
+// tenant config is read from db, missing values fall back to environment.
+func loadTwilioConfig(tenant string, mongoClient odm.MongoClient) (*db.TwilioConfigModel, error) {
+	config, err := async.Await(odm.CollectionOf[db.TwilioConfigModel](mongoClient, tenant).FindOneByID(context.Background(), db.TwilioConfigModel{}.Id()))
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		config = &db.TwilioConfigModel{}
+	}
+
+	if config.AccountSid == "" {
+		config.AccountSid = os.Getenv("TWILIO-ACCOUNT-SID")
+	}
+	if config.VerifyServiceSid == "" {
+		config.VerifyServiceSid = os.Getenv("TWILIO-VERIFY-SERVICE-SID")
+	}
+	if config.MessagingServiceSid == "" {
+		config.MessagingServiceSid = os.Getenv("TWILIO-MESSAGING-SERVICE-SID")
+	}
+
+	if config.AccountSid == "" {
+		return nil, errors.New("twilio is not configured for tenant " + tenant)
+	}
+	return config, nil
+}
+
+// auth token of an account is read from TWILIO-AUTH-TOKEN-<accountSid> falling back to TWILIO-AUTH-TOKEN.
+func getTwilioAuthToken(accountSid string) string {
+	if token := os.Getenv("TWILIO-AUTH-TOKEN-" + accountSid); token != "" {
+		return token
+	}
+	return os.Getenv("TWILIO-AUTH-TOKEN")
+}
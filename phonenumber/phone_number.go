@@ -0,0 +1,67 @@
+package phonenumber
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// region used when neither the number nor tenant config has one.
+const DefaultRegion = "IN"
+
+var phoneLike = regexp.MustCompile(`^\+?[0-9 ()\-.]{6,20}$`)
+
+// IsPhoneNumber reports whether the value looks like a phone number rather than an email.
+func IsPhoneNumber(value string) bool {
+	return phoneLike.MatchString(strings.TrimSpace(value))
+}
+
+// Normalize parses a phone number with or without +CC and returns it in E.164 format.
+// Numbers without +CC are parsed using the defaultRegion.
+func Normalize(number, defaultRegion string) (string, error) {
+	if defaultRegion == "" {
+		defaultRegion = DefaultRegion
+	}
+
+	parsed, err := phonenumbers.Parse(strings.TrimSpace(number), strings.ToUpper(defaultRegion))
+	if err != nil {
+		return "", err
+	}
+
+	if !phonenumbers.IsValidNumber(parsed) {
+		return "", errors.New("invalid phone number")
+	}
+
+	return phonenumbers.Format(parsed, phonenumbers.E164), nil
+}
+
+// IsE164 reports whether the number is a valid number already normalized to E.164.
+func IsE164(number string) bool {
+	if !strings.HasPrefix(number, "+") {
+		return false
+	}
+
+	normalized, err := Normalize(number, "")
+	return err == nil && normalized == number
+}
+
+// country code of DefaultRegion, the only region whose numbers were stored without +CC.
+const legacyCountryCode = 91
+
+// Variants returns the E.164 number along with the national number for +91 numbers.
+// Records created before normalization stored only the national number, always of DefaultRegion.
+// Other regions get no national variant, it could match a different subscriber's legacy record.
+func Variants(e164 string) []string {
+	parsed, err := phonenumbers.Parse(e164, "")
+	if err != nil || parsed.GetCountryCode() != legacyCountryCode {
+		return []string{e164}
+	}
+
+	national := phonenumbers.GetNationalSignificantNumber(parsed)
+	if national == "" || national == e164 {
+		return []string{e164}
+	}
+	return []string{e164, national}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// networks of proxies whose forwarded headers are trusted. Set once at startup.
+var trustedProxies []*net.IPNet
+
+// TrustProxies sets the networks of proxies allowed to forward the client ip.
+// Format: 10.0.0.0/8,192.168.1.10/32. Forwarded headers are ignored when empty.
+func TrustProxies(cidrs string) error {
+	proxies := []*net.IPNet{}
+	for _, cidr := range strings.Split(cidrs, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		proxies = append(proxies, network)
+	}
+
+	trustedProxies = proxies
+	return nil
+}
+
+// ProxiesTrusted reports if trusted proxies are configured.
+// Behind a proxy the peer of every call is the proxy, so the client ip is only known when set.
+func ProxiesTrusted() bool {
+	return len(trustedProxies) > 0
+}
+
+// ClientIp returns the caller ip. Forwarded metadata is honoured only when the grpc peer is a trusted proxy.
+func ClientIp(ctx context.Context) string {
+	peerIp := peerAddress(ctx)
+	if !isTrustedProxy(peerIp) {
+		return peerIp
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		// every proxy appends the address it received the request from, so entries left of the
+		// right-most untrusted hop can be forged by the client.
+		hops := strings.Split(strings.Join(md.Get("x-forwarded-for"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && !isTrustedProxy(hop) {
+				return hop
+			}
+		}
+
+		if realIp := md.Get("x-real-ip"); len(realIp) > 0 && strings.TrimSpace(realIp[0]) != "" {
+			return strings.TrimSpace(realIp[0])
+		}
+	}
+
+	return peerIp
+}
+
+func peerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			return p.Addr.String()
+		}
+		return host
+	}
+
+	return "unknown"
+}
+
+func isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.uber.org/zap"
+)
+
+// Limit allows Max hits of a key in a sliding Window.
+type Limit struct {
+	Name   string
+	Max    int64
+	Window time.Duration
+}
+
+// Limiter is a sliding window rate limiter backed by mongo so that limits hold across replicas.
+// It approximates the sliding window from counts of the current and previous fixed windows.
+type Limiter struct {
+	mongo   odm.MongoClient
+	indexed sync.Map
+}
+
+func ProvideLimiter(mongo odm.MongoClient) *Limiter {
+	return &Limiter{mongo: mongo}
+}
+
+// Allow records a hit of key against the limit.
+// Returns false with the time to wait if the limit is exceeded.
+func (l *Limiter) Allow(ctx context.Context, tenant string, limit Limit, key string) (bool, time.Duration, error) {
+	l.ensureIndex(ctx, tenant)
+
+	window := getWindow(limit, key)
+
+	// counters outlive the window so that they can be used as previous window.
+	current, err := db.IncrementRateLimitCounter(ctx, l.mongo, tenant, window.currentKey, window.start.Add(2*limit.Window))
+	if err != nil {
+		return false, 0, err
+	}
+
+	previous, err := db.GetRateLimitCount(ctx, l.mongo, tenant, window.previousKey)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if window.estimate(previous, current) <= float64(limit.Max) {
+		return true, 0, nil
+	}
+
+	return false, limit.Window - window.elapsed, nil
+}
+
+// Refund takes back a hit allowed by Allow, for hits whose action failed.
+func (l *Limiter) Refund(ctx context.Context, tenant string, limit Limit, key string) error {
+	return db.DecrementRateLimitCounter(ctx, l.mongo, tenant, getWindow(limit, key).currentKey)
+}
+
+// Remaining returns hits left for key in the sliding window without recording a hit.
+func (l *Limiter) Remaining(ctx context.Context, tenant string, limit Limit, key string) (int64, error) {
+	window := getWindow(limit, key)
+
+	current, err := db.GetRateLimitCount(ctx, l.mongo, tenant, window.currentKey)
+	if err != nil {
+		return 0, err
+	}
+
+	previous, err := db.GetRateLimitCount(ctx, l.mongo, tenant, window.previousKey)
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := limit.Max - int64(math.Ceil(window.estimate(previous, current)))
+	if remaining < 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+type slidingWindow struct {
+	start       time.Time
+	elapsed     time.Duration
+	length      time.Duration
+	currentKey  string
+	previousKey string
+}
+
+func getWindow(limit Limit, key string) slidingWindow {
+	now := time.Now()
+	start := now.Truncate(limit.Window)
+
+	return slidingWindow{
+		start:       start,
+		elapsed:     now.Sub(start),
+		length:      limit.Window,
+		currentKey:  fmt.Sprintf("%s:%s:%d", limit.Name, key, start.Unix()),
+		previousKey: fmt.Sprintf("%s:%s:%d", limit.Name, key, start.Add(-limit.Window).Unix()),
+	}
+}
+
+// weighs previous window count by its overlap with the sliding window.
+func (w slidingWindow) estimate(previous, current int64) float64 {
+	previousWeight := 1 - float64(w.elapsed)/float64(w.length)
+	return float64(previous)*previousWeight + float64(current)
+}
+
+// creates TTL index once per tenant database.
+func (l *Limiter) ensureIndex(ctx context.Context, tenant string) {
+	if _, done := l.indexed.LoadOrStore(tenant, true); done {
+		return
+	}
+
+	for _, collection := range []string{db.RateLimitCounterModel{}.CollectionName(), db.LockoutModel{}.CollectionName()} {
+		if err := db.EnsureExpiryIndex(ctx, l.mongo, tenant, collection); err != nil {
+			logger.Error("Failed creating expiry index", zap.String("collection", collection), zap.Error(err))
+			l.indexed.Delete(tenant)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package ratelimit
+
+import "time"
+
+// limits applied on login (otp send) and verify per email/phone, caller ip and tenant.
+var (
+	LoginPerIdentifier = Limit{Name: "login:identifier", Max: 5, Window: 15 * time.Minute}
+	LoginPerIp         = Limit{Name: "login:ip", Max: 30, Window: 15 * time.Minute}
+	LoginPerTenant     = Limit{Name: "login:tenant", Max: 600, Window: time.Minute}
+
+	VerifyPerIdentifier = Limit{Name: "verify:identifier", Max: 10, Window: 15 * time.Minute}
+	VerifyPerIp         = Limit{Name: "verify:ip", Max: 60, Window: 15 * time.Minute}
+	VerifyPerTenant     = Limit{Name: "verify:tenant", Max: 1200, Window: time.Minute}
+
+	// otps actually sent to an email/phone in a day.
+	OtpSendsPerDay = Limit{Name: "otp:daily", Max: 10, Window: 24 * time.Hour}
+
+	// personal data exports a user can create in a day.
+	DataExportsPerUser = Limit{Name: "export:user", Max: 3, Window: 24 * time.Hour}
+)
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// failed verifications after which identifier is locked.
+	lockoutThreshold = 5
+	// first lockout duration, doubled on every subsequent lockout.
+	baseLockout = 5 * time.Minute
+	maxLockout  = 24 * time.Hour
+	// lockout levels are forgotten after this long without failures.
+	lockoutMemory = 7 * 24 * time.Hour
+)
+
+// LockedFor returns how long the identifier remains locked, 0 if not locked.
+func (l *Limiter) LockedFor(ctx context.Context, tenant, identifier string) (time.Duration, error) {
+	lockout, err := async.Await(odm.CollectionOf[db.LockoutModel](l.mongo, tenant).FindOneByID(ctx, identifier))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := time.Until(time.Unix(lockout.LockedUntil, 0))
+	if remaining < 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+// RecordFailure records a failed verification and locks the identifier on reaching the threshold.
+// Returns the lockout duration if this failure locked the identifier.
+func (l *Limiter) RecordFailure(ctx context.Context, tenant, identifier string) (time.Duration, error) {
+	l.ensureIndex(ctx, tenant)
+
+	expireAt := time.Now().Add(lockoutMemory)
+	lockout, err := db.IncrementFailedAttempts(ctx, l.mongo, tenant, identifier, expireAt)
+	if err != nil {
+		return 0, err
+	}
+
+	if lockout.FailedAttempts < lockoutThreshold {
+		return 0, nil
+	}
+
+	duration := baseLockout << lockout.Level
+	if duration > maxLockout || duration <= 0 {
+		duration = maxLockout
+	}
+
+	locked, err := db.Lock(ctx, l.mongo, tenant, identifier, lockoutThreshold, time.Now().Add(duration).Unix(), expireAt)
+	if err != nil || !locked {
+		return 0, err
+	}
+	return duration, nil
+}
+
+func (l *Limiter) RecordSuccess(ctx context.Context, tenant, identifier string) error {
+	return db.ResetFailedAttempts(ctx, l.mongo, tenant, identifier)
+}
+
+// Unlock removes lockout of the identifier along with its escalation level.
+func (l *Limiter) Unlock(ctx context.Context, tenant, identifier string) error {
+	_, err := async.Await(odm.CollectionOf[db.LockoutModel](l.mongo, tenant).DeleteByID(ctx, identifier))
+	return err
+}
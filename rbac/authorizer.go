@@ -0,0 +1,181 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/superadmin"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Authorizer resolves permissions of users from the roles assigned to them.
+type Authorizer struct {
+	mongo odm.MongoClient
+}
+
+func ProvideAuthorizer(mongo odm.MongoClient) *Authorizer {
+	return &Authorizer{mongo: mongo}
+}
+
+// Authorize checks that the caller has the permission in their tenant.
+// Returns caller's user id and tenant, or a PermissionDenied error.
+func (a *Authorizer) Authorize(ctx context.Context, permission string) (string, string, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if err := a.Check(ctx, tenant, userId, permission); err != nil {
+		return "", "", err
+	}
+	return userId, tenant, nil
+}
+
+// Check returns a PermissionDenied error if the user does not have the permission.
+// Super admins calling against a tenant have every permission in it.
+func (a *Authorizer) Check(ctx context.Context, tenant, userId, permission string) error {
+	if isSuperAdminCall(ctx, tenant, userId) {
+		return nil
+	}
+
+	allowed, err := a.HasPermission(ctx, tenant, userId, permission)
+	if err != nil {
+		logger.Error("Failed checking permission", zap.String("userId", userId), zap.String("permission", permission), zap.Error(err))
+		return status.Error(codes.Internal, "Failed checking permission")
+	}
+	if !allowed {
+		return status.Error(codes.PermissionDenied, "User with id "+userId+" don't have permission "+permission)
+	}
+	return nil
+}
+
+// CheckAdmin returns a PermissionDenied error unless the user holds the admin role.
+func (a *Authorizer) CheckAdmin(ctx context.Context, tenant, userId string) error {
+	if isSuperAdminCall(ctx, tenant, userId) {
+		return nil
+	}
+
+	isAdmin, err := a.HasRole(ctx, tenant, userId, RoleAdmin)
+	if err != nil {
+		logger.Error("Failed checking role", zap.String("userId", userId), zap.String("role", RoleAdmin), zap.Error(err))
+		return status.Error(codes.Internal, "Failed checking permission")
+	}
+	if !isAdmin {
+		return status.Error(codes.PermissionDenied, "Only admins can grant or change role "+RoleAdmin)
+	}
+	return nil
+}
+
+// CheckGrant returns a PermissionDenied error unless the user has every one of the permissions,
+// so that users cannot hand out permissions they don't have.
+func (a *Authorizer) CheckGrant(ctx context.Context, tenant, userId string, permissions []string) error {
+	if isSuperAdminCall(ctx, tenant, userId) {
+		return nil
+	}
+
+	held, err := a.Permissions(ctx, tenant, userId)
+	if err != nil {
+		logger.Error("Failed checking permission", zap.String("userId", userId), zap.Error(err))
+		return status.Error(codes.Internal, "Failed checking permission")
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(held, permission) {
+			return status.Error(codes.PermissionDenied, "User with id "+userId+" cannot grant permission "+permission+" they don't have")
+		}
+	}
+	return nil
+}
+
+// super admins calling against a tenant have every permission in it.
+func isSuperAdminCall(ctx context.Context, tenant, userId string) bool {
+	call, ok := superadmin.CallFromContext(ctx)
+	return ok && call.TargetTenant == tenant && call.UserId == userId
+}
+
+func (a *Authorizer) HasPermission(ctx context.Context, tenant, userId, permission string) (bool, error) {
+	permissions, err := a.Permissions(ctx, tenant, userId)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(permissions, permission), nil
+}
+
+// HasRole reports if the role is assigned to the user.
+func (a *Authorizer) HasRole(ctx context.Context, tenant, userId, role string) (bool, error) {
+	roles, err := a.Roles(ctx, tenant, userId)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(roles, role), nil
+}
+
+// Roles returns roles assigned to the user. Unknown users have none.
+func (a *Authorizer) Roles(ctx context.Context, tenant, userId string) ([]string, error) {
+	if userId == "" {
+		return nil, nil
+	}
+
+	loginInfo, err := async.Await(odm.CollectionOf[db.LoginModel](a.mongo, tenant).FindOneByID(ctx, userId))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	roles := slices.Clone(loginInfo.Roles)
+	if loginInfo.UserType == AdminUserType && !slices.Contains(roles, RoleAdmin) {
+		roles = append(roles, RoleAdmin)
+	}
+	return roles, nil
+}
+
+// Permissions returns the union of permissions of the user's roles.
+func (a *Authorizer) Permissions(ctx context.Context, tenant, userId string) ([]string, error) {
+	roles, err := a.Roles(ctx, tenant, userId)
+	if err != nil || len(roles) == 0 {
+		return nil, err
+	}
+	if slices.Contains(roles, RoleAdmin) {
+		return AllPermissions, nil
+	}
+
+	roleModels, err := async.Await(odm.CollectionOf[db.RoleModel](a.mongo, tenant).Find(ctx, bson.M{"_id": bson.M{"$in": roles}}, nil, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := []string{}
+	for _, role := range roleModels {
+		for _, permission := range role.Permissions {
+			if !slices.Contains(permissions, permission) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions, nil
+}
+
+// ListRoles returns the tenant's roles, built in admin role first.
+func (a *Authorizer) ListRoles(ctx context.Context, tenant string) ([]db.RoleModel, error) {
+	roles, err := async.Await(odm.CollectionOf[db.RoleModel](a.mongo, tenant).Find(ctx, bson.M{}, bson.D{{Key: "_id", Value: 1}}, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	return append([]db.RoleModel{AdminRole()}, roles...), nil
+}
+
+// AdminRole is the built in role, it is not stored.
+func AdminRole() db.RoleModel {
+	return db.RoleModel{
+		Name:        RoleAdmin,
+		Description: "Built in role with every permission",
+		Permissions: AllPermissions,
+	}
+}
@@ -0,0 +1,55 @@
+package rbac
+
+import (
+	"regexp"
+	"slices"
+)
+
+// permissions checked by the services.
+const (
+	PermLeadsRead          = "leads.read"
+	PermLeadsWrite         = "leads.write"
+	PermUsersRead          = "users.read"
+	PermUsersBlock         = "users.block"
+	PermUsersDelete        = "users.delete"
+	PermUsersChangeType    = "users.changeType"
+	PermSessionsRevoke     = "sessions.revoke"
+	PermTestAccountsManage = "testAccounts.manage"
+	PermProfileMasterRead  = "profileMaster.read"
+	PermProfileMasterEdit  = "profileMaster.edit"
+	PermRolesManage        = "roles.manage"
+	// only granted in the platform tenant.
+	PermTenantsManage = "tenants.manage"
+)
+
+var AllPermissions = []string{
+	PermLeadsRead,
+	PermLeadsWrite,
+	PermUsersRead,
+	PermUsersBlock,
+	PermUsersDelete,
+	PermUsersChangeType,
+	PermSessionsRevoke,
+	PermTestAccountsManage,
+	PermProfileMasterRead,
+	PermProfileMasterEdit,
+	PermRolesManage,
+	PermTenantsManage,
+}
+
+// RoleAdmin is built in and has every permission. Users with user type admin hold it
+// without an assignment, so that admins from before roles keep their access.
+const RoleAdmin = "admin"
+
+// AdminUserType is the user type holding RoleAdmin without an assignment.
+const AdminUserType = "admin"
+
+var roleNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]{1,63}$`)
+
+func IsValidPermission(permission string) bool {
+	return slices.Contains(AllPermissions, permission)
+}
+
+func IsValidRoleName(name string) bool {
+	return roleNamePattern.MatchString(name)
+}
@@ -0,0 +1,103 @@
+package rbac
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Policy declares who can call a grpc method.
+type Policy struct {
+	// public methods authenticate callers themselves, if at all.
+	Public bool
+	// permission required from the caller, empty if any authenticated user is allowed.
+	Permission string
+}
+
+var (
+	Public        = Policy{Public: true}
+	Authenticated = Policy{}
+)
+
+func Require(permission string) Policy {
+	return Policy{Permission: permission}
+}
+
+// MethodPolicies maps full grpc method names ("/package.Service/Method") to their policy.
+type MethodPolicies map[string]Policy
+
+// Add declares policies of the service's methods by their short names.
+func (p MethodPolicies) Add(desc grpc.ServiceDesc, policies map[string]Policy) MethodPolicies {
+	for method, policy := range policies {
+		p["/"+desc.ServiceName+"/"+method] = policy
+	}
+	return p
+}
+
+// PolicyRegistry holds method policies and records services registered on the grpc server,
+// so that methods without a policy are found at startup.
+type PolicyRegistry struct {
+	policies MethodPolicies
+	lock     sync.Mutex
+	methods  []string
+}
+
+func ProvidePolicyRegistry(policies MethodPolicies) *PolicyRegistry {
+	return &PolicyRegistry{policies: policies}
+}
+
+// Get returns policy of the full method name. Methods without a policy are not found.
+func (r *PolicyRegistry) Get(fullMethod string) (Policy, bool) {
+	policy, ok := r.policies[fullMethod]
+	return policy, ok
+}
+
+// Track wraps a service registration so that methods of the service are recorded.
+func (r *PolicyRegistry) Track(register func(grpc.ServiceRegistrar, any)) func(grpc.ServiceRegistrar, any) {
+	return func(registrar grpc.ServiceRegistrar, srv any) {
+		register(&trackingRegistrar{ServiceRegistrar: registrar, registry: r}, srv)
+	}
+}
+
+// Verify fails if any registered method has no policy.
+func (r *PolicyRegistry) Verify() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	missing := []string{}
+	for _, method := range r.methods {
+		if _, ok := r.policies[method]; !ok {
+			missing = append(missing, method)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return errors.New("methods without authorization policy: " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (r *PolicyRegistry) record(desc *grpc.ServiceDesc) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, method := range desc.Methods {
+		r.methods = append(r.methods, "/"+desc.ServiceName+"/"+method.MethodName)
+	}
+	for _, stream := range desc.Streams {
+		r.methods = append(r.methods, "/"+desc.ServiceName+"/"+stream.StreamName)
+	}
+}
+
+type trackingRegistrar struct {
+	grpc.ServiceRegistrar
+	registry *PolicyRegistry
+}
+
+func (t *trackingRegistrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	t.registry.record(desc)
+	t.ServiceRegistrar.RegisterService(desc, impl)
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"os"
+	"slices"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authenticateClient checks x-client-id and x-client-secret metadata of a calling service
+// against the allowed clients and returns the client id.
+// Secret of each client is read from INTROSPECTION-SECRET-<client> env variable.
+func authenticateClient(ctx context.Context, clients []string) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	clientId := firstValue(md, "x-client-id")
+	clientSecret := firstValue(md, "x-client-secret")
+
+	if clientId == "" || !slices.Contains(clients, clientId) {
+		return "", status.Error(codes.Unauthenticated, "Unknown client")
+	}
+
+	expected := os.Getenv("INTROSPECTION-SECRET-" + clientId)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(clientSecret)) != 1 {
+		logger.Error("Invalid client secret", zap.String("clientId", clientId))
+		return "", status.Error(codes.Unauthenticated, "Invalid client secret")
+	}
+	return clientId, nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
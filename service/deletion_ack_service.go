@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/deletion"
+	authPb "github.com/Kotlang/authGo/generated/auth"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type clientIdKey struct{}
+
+// DeletionAckService lets downstream services report that they removed data of a deleted user.
+// Callers authenticate with their client id and secret instead of a user token.
+type DeletionAckService struct {
+	authPb.UnimplementedDeletionAckServer
+	mongo  odm.MongoClient
+	fanout *deletion.Fanout
+}
+
+func ProvideDeletionAckService(mongo odm.MongoClient, fanout *deletion.Fanout) *DeletionAckService {
+	return &DeletionAckService{
+		mongo:  mongo,
+		fanout: fanout,
+	}
+}
+
+// authenticates calling service with x-client-id and x-client-secret metadata.
+func (s *DeletionAckService) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	clientId, err := authenticateClient(ctx, s.fanout.Subscribers())
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, clientIdKey{}, clientId), nil
+}
+
+// caller is a service, not a user.
+func (s *DeletionAckService) CheckUserExistenceOverride(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+// AcknowledgeDeletion records the calling service's result of handling a user.deleted event.
+func (s *DeletionAckService) AcknowledgeDeletion(ctx context.Context, req *authPb.AcknowledgeDeletionRequest) (*authPb.StatusResponse, error) {
+	clientId, _ := ctx.Value(clientIdKey{}).(string)
+
+	if req.Status != db.DeletionAckDone && req.Status != db.DeletionAckFailed {
+		return nil, status.Error(codes.InvalidArgument, "Status must be done or failed")
+	}
+	if !tenant.IsValidName(req.Tenant) || req.EventId == "" {
+		return nil, status.Error(codes.InvalidArgument, "Tenant and event id are required")
+	}
+
+	found, err := s.fanout.Acknowledge(ctx, req.Tenant, req.EventId, clientId, req.Status, req.Details)
+	if err != nil {
+		logger.Error("Failed acknowledging deletion", zap.String("eventId", req.EventId), zap.String("service", clientId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed acknowledging deletion")
+	}
+	if !found {
+		return nil, status.Error(codes.NotFound, "Deletion event not found")
+	}
+
+	db.SaveAuditLog(ctx, s.mongo, req.Tenant, db.AuditLogModel{
+		Action:  db.AuditDeletionAcknowledged,
+		ActorId: clientId,
+		Target:  req.EventId,
+		Details: map[string]string{"status": req.Status},
+	})
+
+	return &authPb.StatusResponse{Status: "Acknowledged"}, nil
+}
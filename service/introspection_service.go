@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kotlang/authGo/apierror"
+	"github.com/Kotlang/authGo/appconfig"
+	authPb "github.com/Kotlang/authGo/generated/auth"
+	"github.com/Kotlang/authGo/session"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/Kotlang/authGo/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// introspection results are reused for this long, so a revocation is seen by callers within it.
+const introspectionCacheTtl = 10 * time.Second
+
+type cachedIntrospection struct {
+	response  *authPb.IntrospectTokenResponse
+	checkedAt time.Time
+}
+
+// IntrospectionService lets sibling services check whether an access token is still active.
+// Callers authenticate with their client id and secret instead of a user token.
+type IntrospectionService struct {
+	authPb.UnimplementedIntrospectionServer
+	keys     *token.KeyStore
+	sessions *session.Store
+	tenants  *tenant.Registry
+	clients  []string
+	// token hash -> cachedIntrospection
+	cache     sync.Map
+	lastSweep time.Time
+	sweepLock sync.Mutex
+}
+
+func ProvideIntrospectionService(ccfg *appconfig.AppConfig, keys *token.KeyStore, sessions *session.Store, tenants *tenant.Registry) *IntrospectionService {
+	clients := []string{}
+	for _, client := range strings.Split(ccfg.IntrospectionClients, ",") {
+		if client = strings.TrimSpace(client); client != "" {
+			clients = append(clients, client)
+		}
+	}
+
+	return &IntrospectionService{
+		keys:     keys,
+		sessions: sessions,
+		tenants:  tenants,
+		clients:  clients,
+	}
+}
+
+// authenticates calling service with x-client-id and x-client-secret metadata.
+func (s *IntrospectionService) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	if _, err := authenticateClient(ctx, s.clients); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// caller is a service, not a user.
+func (s *IntrospectionService) CheckUserExistenceOverride(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+// IntrospectToken returns whether the access token is active with its claims.
+// It applies the same session and user checks as authenticated calls.
+func (s *IntrospectionService) IntrospectToken(ctx context.Context, req *authPb.IntrospectTokenRequest) (*authPb.IntrospectTokenResponse, error) {
+	sum := sha256.Sum256([]byte(req.Token))
+	cacheKey := hex.EncodeToString(sum[:])
+
+	s.sweepCache()
+	if cached, ok := s.cache.Load(cacheKey); ok {
+		entry := cached.(cachedIntrospection)
+		if time.Since(entry.checkedAt) < introspectionCacheTtl && (!entry.response.Active || time.Now().Unix() < entry.response.ExpiresOn) {
+			return entry.response, nil
+		}
+	}
+
+	claims := &token.Claims{}
+	if err := s.keys.Parse(ctx, req.Token, claims); err != nil {
+		return &authPb.IntrospectTokenResponse{Active: false, InactiveReason: apierror.ReasonInvalidToken}, nil
+	}
+
+	res := &authPb.IntrospectTokenResponse{
+		Subject:   claims.ID,
+		Tenant:    claims.Tenant(),
+		UserType:  claims.Subject,
+		SessionId: claims.SessionId,
+		ExpiresOn: claims.ExpiresOn(),
+	}
+
+	err := s.tenants.CheckActive(ctx, claims.Tenant())
+	if err == nil {
+		_, err = s.sessions.CheckAccess(ctx, claims.Tenant(), claims)
+	}
+	if err != nil {
+		// state could not be checked, caller should retry instead of treating token as inactive.
+		if status.Code(err) == codes.Unavailable {
+			return nil, err
+		}
+		res.InactiveReason = apierror.ReasonOf(err)
+	} else {
+		res.Active = true
+	}
+
+	s.cache.Store(cacheKey, cachedIntrospection{response: res, checkedAt: time.Now()})
+	return res, nil
+}
+
+// drops stale entries at most once a minute.
+func (s *IntrospectionService) sweepCache() {
+	s.sweepLock.Lock()
+	if time.Since(s.lastSweep) < time.Minute {
+		s.sweepLock.Unlock()
+		return
+	}
+	s.lastSweep = time.Now()
+	s.sweepLock.Unlock()
+
+	s.cache.Range(func(key, value any) bool {
+		if time.Since(value.(cachedIntrospection).checkedAt) >= introspectionCacheTtl {
+			s.cache.Delete(key)
+		}
+		return true
+	})
+}
@@ -5,35 +5,29 @@ import (
 
 	"github.com/Kotlang/authGo/db"
 	authPb "github.com/Kotlang/authGo/generated/auth"
+	"github.com/Kotlang/authGo/token"
 	"github.com/SaiNageswarS/go-api-boot/async"
 	"github.com/SaiNageswarS/go-api-boot/auth"
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
 	"github.com/jinzhu/copier"
 	"go.uber.org/zap"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type LeadService struct {
 	authPb.UnimplementedLeadServiceServer
+	tokenAuth
 	mongo odm.MongoClient
 }
 
-func ProvideLeadService(mongo odm.MongoClient) *LeadService {
-	return &LeadService{mongo: mongo}
+func ProvideLeadService(mongo odm.MongoClient, keys *token.KeyStore) *LeadService {
+	return &LeadService{tokenAuth: tokenAuth{keys: keys}, mongo: mongo}
 }
 
 // Admin only API
 func (s *LeadService) CreateLead(ctx context.Context, req *authPb.CreateOrUpdateLeadRequest) (*authPb.LeadProto, error) {
 
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
-
-	// check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		logger.Error("User is not admin", zap.String("userId", userId))
-		return nil, status.Error(codes.PermissionDenied, "User is not admin")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 	// get the lead model from the request
 	lead := getLeadModel(req)
 
@@ -53,13 +47,7 @@ func (s *LeadService) CreateLead(ctx context.Context, req *authPb.CreateOrUpdate
 
 // Admin only API
 func (s *LeadService) GetLeadById(ctx context.Context, req *authPb.LeadIdRequest) (*authPb.LeadProto, error) {
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
-
-	// check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		logger.Error("User is not admin", zap.String("userId", userId))
-		return nil, status.Error(codes.PermissionDenied, "User is not admin")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
 	// get the lead from db
 	lead, err := async.Await(odm.CollectionOf[db.LeadModel](s.mongo, tenant).FindOneByID(ctx, req.LeadId))
@@ -74,13 +62,7 @@ func (s *LeadService) GetLeadById(ctx context.Context, req *authPb.LeadIdRequest
 
 // Admin only API
 func (s *LeadService) BulkGetLeadsById(ctx context.Context, req *authPb.BulkIdRequest) (*authPb.LeadListResponse, error) {
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
-
-	// check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		logger.Error("User is not admin", zap.String("userId", userId))
-		return nil, status.Error(codes.PermissionDenied, "User is not admin")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
 	// get the leads from db
 	leads, err := async.Await(db.FindLeadsByIds(ctx, s.mongo, tenant, req.LeadIds))
@@ -98,13 +80,7 @@ func (s *LeadService) BulkGetLeadsById(ctx context.Context, req *authPb.BulkIdRe
 
 // Admin only API
 func (s *LeadService) UpdateLead(ctx context.Context, req *authPb.CreateOrUpdateLeadRequest) (*authPb.LeadProto, error) {
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
-
-	// check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		logger.Error("User is not admin", zap.String("userId", userId))
-		return nil, status.Error(codes.PermissionDenied, "User is not admin")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
 	// get the lead model from the request
 	lead := getLeadModel(req)
@@ -125,13 +101,7 @@ func (s *LeadService) UpdateLead(ctx context.Context, req *authPb.CreateOrUpdate
 
 // Admin only API
 func (s *LeadService) DeleteLead(ctx context.Context, req *authPb.LeadIdRequest) (*authPb.StatusResponse, error) {
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
-
-	// check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		logger.Error("User is not admin", zap.String("userId", userId))
-		return nil, status.Error(codes.PermissionDenied, "User is not admin")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
 	// delete the lead
 	_, err := async.Await(odm.CollectionOf[db.LeadModel](s.mongo, tenant).DeleteByID(ctx, req.LeadId))
@@ -147,13 +117,7 @@ func (s *LeadService) DeleteLead(ctx context.Context, req *authPb.LeadIdRequest)
 
 // Admin only API
 func (s *LeadService) FetchLeads(ctx context.Context, req *authPb.FetchLeadsRequest) (*authPb.LeadListResponse, error) {
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
-
-	// check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		logger.Error("User is not admin", zap.String("userId", userId))
-		return nil, status.Error(codes.PermissionDenied, "User is not admin")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
 	if req.PageNumber < 0 {
 		req.PageNumber = 0
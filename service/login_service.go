@@ -2,14 +2,23 @@ package service
 
 import (
 	"context"
-	"os"
-	"unicode"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/Kotlang/authGo/apierror"
+	"github.com/Kotlang/authGo/appconfig"
 	"github.com/Kotlang/authGo/db"
 	authPb "github.com/Kotlang/authGo/generated/auth"
 	"github.com/Kotlang/authGo/otp"
+	"github.com/Kotlang/authGo/phonenumber"
+	"github.com/Kotlang/authGo/ratelimit"
+	"github.com/Kotlang/authGo/session"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/Kotlang/authGo/token"
 	"github.com/SaiNageswarS/go-api-boot/async"
-	"github.com/SaiNageswarS/go-api-boot/auth"
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
 	"github.com/jinzhu/copier"
@@ -20,17 +29,33 @@ import (
 
 type LoginService struct {
 	authPb.UnimplementedLoginServer
-	mongo odm.MongoClient
-	otp   otp.OtpClientInterface
+	mongo        odm.MongoClient
+	otp          otp.OtpClientInterface
+	ccfg         *appconfig.AppConfig
+	limiter      *ratelimit.Limiter
+	testAccounts *otp.TestAccounts
+	sessions     *session.Store
+	keys         *token.KeyStore
+	tenants      *tenant.Registry
 }
 
 func ProvideLoginService(
 	mongo odm.MongoClient,
-	otp otp.OtpClientInterface) *LoginService {
+	otpClient otp.OtpClientInterface,
+	ccfg *appconfig.AppConfig,
+	sessions *session.Store,
+	keys *token.KeyStore,
+	tenants *tenant.Registry) *LoginService {
 
 	return &LoginService{
-		mongo: mongo,
-		otp:   otp,
+		mongo:        mongo,
+		otp:          otpClient,
+		ccfg:         ccfg,
+		limiter:      ratelimit.ProvideLimiter(mongo),
+		testAccounts: otp.ProvideTestAccounts(mongo),
+		sessions:     sessions,
+		keys:         keys,
+		tenants:      tenants,
 	}
 }
 
@@ -49,21 +74,50 @@ func (s *LoginService) Login(ctx context.Context, req *authPb.LoginRequest) (*au
 		return nil, status.Error(codes.InvalidArgument, "Invalid Domain Token")
 	}
 
+	// checked before anything is written so that unknown domains don't create databases.
+	if err := s.tenants.CheckActive(ctx, req.Domain); err != nil {
+		return nil, err
+	}
+
+	emailOrPhone, err := normalizeEmailOrPhone(s.ccfg, req.Domain, req.EmailOrPhone)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.enforceRateLimits(ctx, req.Domain, emailOrPhone, ratelimit.LoginPerIdentifier, ratelimit.LoginPerIp, ratelimit.LoginPerTenant)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := s.tenants.Policy(ctx, req.Domain)
+	if err != nil {
+		logger.Error("Failed getting tenant policy", zap.String("tenant", req.Domain), zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "Failed checking domain")
+	}
+	isPhone := phonenumber.IsPhoneNumber(emailOrPhone)
+	if err := checkIdentifierAllowed(policy, isPhone); err != nil {
+		return nil, err
+	}
+
 	// get login details by phone or email
-	isPhone := isPhoneNumber(req.EmailOrPhone)
 	var loginDetails *db.LoginModel
 	if isPhone {
-		loginDetails = <-db.FindOneByPhoneOrEmail(ctx, s.mongo, req.Domain, req.EmailOrPhone, "")
+		loginDetails = <-db.FindOneByPhoneOrEmail(ctx, s.mongo, req.Domain, emailOrPhone, "")
 	} else {
-		loginDetails = <-db.FindOneByPhoneOrEmail(ctx, s.mongo, req.Domain, "", req.EmailOrPhone)
+		loginDetails = <-db.FindOneByPhoneOrEmail(ctx, s.mongo, req.Domain, "", emailOrPhone)
 	}
 
 	// check if user is blocked, if yes return error
-	if loginDetails != nil && loginDetails.IsBlocked {
-		return nil, status.Error(codes.PermissionDenied, "User is blocked")
+	if loginDetails != nil && loginDetails.IsBlockedAt(time.Now().Unix()) {
+		return nil, apierror.UserBlocked(loginDetails.BlockInfo.Reason, loginDetails.BlockInfo.ExpiresOn)
+	}
+
+	// invite only tenants never create logins on request, whatever the client sends.
+	if loginDetails == nil && policy.Registration == db.RegistrationInviteOnly {
+		return nil, apierror.New(codes.NotFound, apierror.ReasonRegistrationClosed, "User does not exist", 0, nil)
 	}
 
-	// if user does not exist and block unknown is true, return error
+	// clients may still ask to restrict to existing users on open tenants.
 	if req.BlockUnknown && loginDetails == nil {
 		return nil, status.Error(codes.NotFound, "User does not exist")
 	}
@@ -73,19 +127,48 @@ func (s *LoginService) Login(ctx context.Context, req *authPb.LoginRequest) (*au
 
 		// if user is marked for deletion, return error
 		if loginDetails.DeletionInfo.MarkedForDeletion {
-			return nil, status.Error(codes.PermissionDenied, "User is marked for deletion")
+			return nil, apierror.New(codes.PermissionDenied, apierror.ReasonUserMarkedForDeletion, "User is marked for deletion", 0, nil)
 		}
 	}
 
+	// accounts being purged can't be restored.
+	if loginDetails != nil && loginDetails.PurgeClaimedOn != 0 {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonUserMarkedForDeletion, "User is marked for deletion", 0, nil)
+	}
+
+	testAccount := s.testAccounts.FindActive(ctx, req.Domain, emailOrPhone)
+
 	if loginDetails == nil {
-		_, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, req.Domain).Save(ctx, db.LoginModel{UserId: req.EmailOrPhone}))
+		newLogin := db.LoginModel{UserId: emailOrPhone, UserType: policy.DefaultUserType, IsTestAccount: testAccount != nil}
+		if isPhone {
+			newLogin.Phone = emailOrPhone
+		} else {
+			newLogin.Email = emailOrPhone
+		}
+
+		_, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, req.Domain).Save(ctx, newLogin))
 		if err != nil {
 			logger.Error("Error saving login info", zap.Error(err))
 		}
 	}
 
+	// test accounts log in with their static code, otp is never sent to them.
+	if testAccount != nil {
+		db.SaveAuditLog(ctx, s.mongo, req.Domain, db.AuditLogModel{
+			Action:  db.AuditTestAccountOtpRequested,
+			Target:  emailOrPhone,
+			Details: map[string]string{"ip": ratelimit.ClientIp(ctx)},
+		})
+		return &authPb.StatusResponse{Status: "success"}, nil
+	}
+
+	delivery := ""
+	if req.DeliveryChannel != authPb.OtpDeliveryChannel_UNSPECIFIED_DELIVERY_CHANNEL {
+		delivery = strings.ToLower(req.DeliveryChannel.String())
+	}
+
 	// send otp
-	err := s.otp.SendOtp(req.Domain, req.EmailOrPhone)
+	err = s.otp.SendOtp(req.Domain, emailOrPhone, delivery, policy)
 	if err != nil {
 		return nil, err
 	}
@@ -97,42 +180,81 @@ func (s *LoginService) Verify(ctx context.Context, req *authPb.VerifyRequest) (*
 		return nil, status.Error(codes.InvalidArgument, "Invalid Domain Token")
 	}
 
-	loginInfo, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, req.Domain).FindOneByID(ctx, req.EmailOrPhone))
+	// checked before anything is written so that unknown domains don't create databases.
+	if err := s.tenants.CheckActive(ctx, req.Domain); err != nil {
+		return nil, err
+	}
+
+	emailOrPhone, err := normalizeEmailOrPhone(s.ccfg, req.Domain, req.EmailOrPhone)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.enforceRateLimits(ctx, req.Domain, emailOrPhone, ratelimit.VerifyPerIdentifier, ratelimit.VerifyPerIp, ratelimit.VerifyPerTenant)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := s.tenants.Policy(ctx, req.Domain)
+	if err != nil {
+		logger.Error("Failed getting tenant policy", zap.String("tenant", req.Domain), zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "Failed checking domain")
+	}
+	if err := checkIdentifierAllowed(policy, phonenumber.IsPhoneNumber(emailOrPhone)); err != nil {
+		return nil, err
+	}
+
+	loginInfo, err := async.Await(db.FindLoginByIdentifier(ctx, s.mongo, req.Domain, emailOrPhone))
 	if err != nil {
 		logger.Error("Error fetching login info", zap.Error(err))
 		return nil, status.Error(codes.NotFound, "User not found")
 	}
 
-	// if phone number is excluded from verification, donot send otp
-	phoneNumberToExcludeVerification := os.Getenv("PHONE_NUMBER_TO_EXCLUDE_VERIFICATION")
-	if req.EmailOrPhone != phoneNumberToExcludeVerification {
-		if loginInfo == nil || !s.otp.ValidateOtp(req.Domain, req.EmailOrPhone, req.Otp) {
-			return nil, status.Error(codes.PermissionDenied, "Wrong OTP")
+	testAccount := s.testAccounts.FindActive(ctx, req.Domain, emailOrPhone)
+	if testAccount != nil {
+		if loginInfo == nil || !s.testAccounts.Verify(testAccount, req.Otp) {
+			return nil, s.recordWrongOtp(ctx, req.Domain, emailOrPhone)
+		}
+
+		userType := loginInfo.UserType
+		if userType == "" {
+			userType = policy.DefaultUserType
+		}
+		if userType != testAccount.UserType {
+			logger.Error("Test account used with different user type", zap.String("emailOrPhone", emailOrPhone), zap.String("userType", userType))
+			return nil, status.Error(codes.PermissionDenied, "Test account is not allowed for user type "+userType)
 		}
+
+		db.SaveAuditLog(ctx, s.mongo, req.Domain, db.AuditLogModel{
+			Action:  db.AuditTestAccountVerified,
+			ActorId: loginInfo.Id(),
+			Target:  emailOrPhone,
+			Details: map[string]string{"ip": ratelimit.ClientIp(ctx)},
+		})
+	} else if loginInfo == nil || !s.otp.ValidateOtp(req.Domain, emailOrPhone, req.Otp) {
+		return nil, s.recordWrongOtp(ctx, req.Domain, emailOrPhone)
 	}
 
-	// if phone number is excluded from verification check if otp is 666666
-	if req.EmailOrPhone == phoneNumberToExcludeVerification && req.Otp != "666666" {
-		return nil, status.Error(codes.PermissionDenied, "Wrong OTP")
+	if err := s.limiter.RecordSuccess(ctx, req.Domain, emailOrPhone); err != nil {
+		logger.Error("Error resetting failed attempts", zap.Error(err))
 	}
 
 	// if user is blocked return error
-	if loginInfo != nil && loginInfo.IsBlocked {
-		return nil, status.Error(codes.PermissionDenied, "User is blocked")
+	if loginInfo != nil && loginInfo.IsBlockedAt(time.Now().Unix()) {
+		return nil, apierror.UserBlocked(loginInfo.BlockInfo.Reason, loginInfo.BlockInfo.ExpiresOn)
 	}
 
-	// if deletion info is marked for deletion, update the deletion info
-	if loginInfo != nil && loginInfo.DeletionInfo.MarkedForDeletion {
-		loginInfo.DeletionInfo.MarkedForDeletion = false
-		loginInfo.DeletionInfo.Reason = ""
-		loginInfo.DeletionInfo.DeletionTime = 0
-
-		// save the login info
-		_, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, req.Domain).Save(ctx, *loginInfo))
-
+	// if deletion info is marked for deletion, restore the account unless its purge has started.
+	if loginInfo != nil && loginInfo.IsDeleting() {
+		restored, err := db.RestoreLogin(ctx, s.mongo, req.Domain, loginInfo.Id())
 		if err != nil {
-			logger.Error("Error saving login info", zap.Error(err))
+			logger.Error("Error restoring login", zap.Error(err))
+			return nil, status.Error(codes.Internal, "Failed restoring account")
+		}
+		if !restored {
+			return nil, apierror.New(codes.PermissionDenied, apierror.ReasonUserMarkedForDeletion, "User is marked for deletion", 0, nil)
 		}
+		loginInfo.DeletionInfo = db.DeletionInfo{}
 	}
 
 	// fetch profile for user.
@@ -147,24 +269,172 @@ func (s *LoginService) Verify(ctx context.Context, req *authPb.VerifyRequest) (*
 	// copy login info to profile even if profile is not present.
 	copier.CopyWithOption(profileProto, loginInfo, copier.Option{IgnoreEmpty: true})
 
-	jwtToken, err := auth.GetToken(req.Domain, loginInfo.Id(), loginInfo.UserType)
+	newSession, refreshToken, err := s.sessions.Create(ctx, req.Domain, loginInfo.Id(), session.DeviceFromContext(ctx), sessionLifetime(policy))
+	if err != nil {
+		logger.Error("Error creating session", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed creating session")
+	}
 
+	jwtToken, expiresOn, err := s.keys.GetAccessToken(ctx, req.Domain, loginInfo.Id(), loginInfo.UserType, newSession.SessionId, loginInfo.TokenEpoch)
 	if err != nil {
 		logger.Error("Error generating jwt token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed generating token")
 	}
 
 	return &authPb.AuthResponse{
-		Jwt:      jwtToken,
-		UserType: loginInfo.UserType,
-		Profile:  profileProto,
+		Jwt:          jwtToken,
+		RefreshToken: refreshToken,
+		ExpiresOn:    expiresOn.Unix(),
+		UserType:     loginInfo.UserType,
+		Profile:      profileProto,
 	}, nil
 }
 
-func isPhoneNumber(emailOrPhone string) bool {
-	for _, c := range emailOrPhone {
-		if !unicode.IsDigit(c) {
-			return false
+// RefreshToken rotates the refresh token and issues a new access token for the session.
+// Reusing a rotated refresh token revokes the session.
+func (s *LoginService) RefreshToken(ctx context.Context, req *authPb.RefreshTokenRequest) (*authPb.AuthResponse, error) {
+	if len(req.Domain) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid Domain Token")
+	}
+
+	// checked before anything is written so that unknown domains don't create databases.
+	if err := s.tenants.CheckActive(ctx, req.Domain); err != nil {
+		return nil, err
+	}
+
+	policy, err := s.tenants.Policy(ctx, req.Domain)
+	if err != nil {
+		logger.Error("Failed getting tenant policy", zap.String("tenant", req.Domain), zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "Failed checking domain")
+	}
+	refreshed, refreshToken, err := s.sessions.Refresh(ctx, req.Domain, req.RefreshToken, sessionLifetime(policy))
+	if errors.Is(err, session.ErrRefreshTokenReused) {
+		logger.Error("Refresh token reused, session revoked", zap.String("sessionId", refreshed.SessionId), zap.String("userId", refreshed.UserId))
+		db.SaveAuditLog(ctx, s.mongo, req.Domain, db.AuditLogModel{
+			Action:  db.AuditRefreshTokenReused,
+			Target:  refreshed.UserId,
+			Details: map[string]string{"sessionId": refreshed.SessionId, "ip": ratelimit.ClientIp(ctx)},
+		})
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonRefreshTokenReused, "Refresh token reused. Login again", 0, nil)
+	}
+	if errors.Is(err, session.ErrInvalidRefreshToken) {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonRefreshTokenInvalid, "Invalid or expired refresh token", 0, nil)
+	}
+	if err != nil {
+		logger.Error("Error refreshing session", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed refreshing session")
+	}
+
+	// user type and token epoch are read again so that changes are reflected in the new access token.
+	loginInfo, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, req.Domain).FindOneByID(ctx, refreshed.UserId))
+	if err != nil {
+		logger.Error("Error fetching login info", zap.String("userId", refreshed.UserId), zap.Error(err))
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonRefreshTokenInvalid, "Invalid or expired refresh token", 0, nil)
+	}
+	if loginInfo.IsBlockedAt(time.Now().Unix()) {
+		return nil, apierror.UserBlocked(loginInfo.BlockInfo.Reason, loginInfo.BlockInfo.ExpiresOn)
+	}
+	if loginInfo.IsDeleting() {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonUserMarkedForDeletion, "User is marked for deletion", 0, nil)
+	}
+
+	jwtToken, expiresOn, err := s.keys.GetAccessToken(ctx, req.Domain, loginInfo.Id(), loginInfo.UserType, refreshed.SessionId, loginInfo.TokenEpoch)
+	if err != nil {
+		logger.Error("Error generating jwt token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed generating token")
+	}
+
+	return &authPb.AuthResponse{
+		Jwt:          jwtToken,
+		RefreshToken: refreshToken,
+		ExpiresOn:    expiresOn.Unix(),
+		UserType:     loginInfo.UserType,
+	}, nil
+}
+
+// rejects identifier types the tenant does not allow.
+func checkIdentifierAllowed(policy db.TenantPolicy, isPhone bool) error {
+	identifier := db.IdentifierEmail
+	if isPhone {
+		identifier = db.IdentifierPhone
+	}
+	if !slices.Contains(policy.AllowedIdentifiers, identifier) {
+		return apierror.New(codes.InvalidArgument, apierror.ReasonIdentifierNotAllowed, "Login with "+identifier+" is not allowed", 0, nil)
+	}
+	return nil
+}
+
+func sessionLifetime(policy db.TenantPolicy) time.Duration {
+	return time.Duration(policy.SessionLifetimeHours) * time.Hour
+}
+
+// checks lockout of the email/phone and sliding window limits of email/phone, caller ip and tenant.
+func (s *LoginService) enforceRateLimits(ctx context.Context, tenant, emailOrPhone string, perIdentifier, perIp, perTenant ratelimit.Limit) error {
+	lockedFor, err := s.limiter.LockedFor(ctx, tenant, emailOrPhone)
+	if err != nil {
+		logger.Error("Error fetching lockout", zap.Error(err))
+		return status.Error(codes.Unavailable, "Failed checking rate limits")
+	}
+	if lockedFor > 0 {
+		return lockedOutError(lockedFor)
+	}
+
+	type keyedLimit struct {
+		limit ratelimit.Limit
+		key   string
+	}
+	limits := []keyedLimit{
+		{perIdentifier, emailOrPhone},
+		{perTenant, tenant},
+	}
+	// without trusted proxies every caller behind the ingress shares its ip, making the limit global.
+	if ratelimit.ProxiesTrusted() {
+		limits = append(limits, keyedLimit{perIp, ratelimit.ClientIp(ctx)})
+	}
+
+	for _, l := range limits {
+		allowed, retryAfter, err := s.limiter.Allow(ctx, tenant, l.limit, l.key)
+		if err != nil {
+			logger.Error("Error checking rate limit", zap.String("limit", l.limit.Name), zap.Error(err))
+			return status.Error(codes.Unavailable, "Failed checking rate limits")
+		}
+		if !allowed {
+			logger.Error("Rate limit exceeded", zap.String("limit", l.limit.Name), zap.String("key", l.key))
+			return apierror.New(codes.ResourceExhausted, apierror.ReasonRateLimited,
+				fmt.Sprintf("Too many requests. Try again in %d seconds", int(retryAfter.Seconds())+1), retryAfter, map[string]string{"limit": l.limit.Name})
 		}
 	}
-	return len(emailOrPhone) == 10
+	return nil
+}
+
+// records failed verification and returns error for the client.
+func (s *LoginService) recordWrongOtp(ctx context.Context, tenant, emailOrPhone string) error {
+	lockedFor, err := s.limiter.RecordFailure(ctx, tenant, emailOrPhone)
+	if err != nil {
+		logger.Error("Error recording failed attempt", zap.Error(err))
+	}
+
+	if lockedFor > 0 {
+		return lockedOutError(lockedFor)
+	}
+	return apierror.New(codes.PermissionDenied, apierror.ReasonWrongOtp, "Wrong OTP", 0, nil)
+}
+
+func lockedOutError(lockedFor time.Duration) error {
+	return apierror.New(codes.PermissionDenied, apierror.ReasonLockedOut,
+		fmt.Sprintf("Too many failed attempts. Try again in %d seconds", int(lockedFor.Seconds())+1), lockedFor, nil)
+}
+
+// phone numbers are normalized to E.164 using tenant's default region.
+func normalizeEmailOrPhone(ccfg *appconfig.AppConfig, tenant, emailOrPhone string) (string, error) {
+	emailOrPhone = strings.TrimSpace(emailOrPhone)
+	if !phonenumber.IsPhoneNumber(emailOrPhone) {
+		return emailOrPhone, nil
+	}
+
+	normalized, err := phonenumber.Normalize(emailOrPhone, ccfg.PhoneRegion(tenant))
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, "Invalid phone number")
+	}
+	return normalized, nil
 }
@@ -2,16 +2,32 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Kotlang/authGo/apierror"
+	"github.com/Kotlang/authGo/appconfig"
+	"github.com/Kotlang/authGo/dataexport"
 	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/deletion"
 	authPb "github.com/Kotlang/authGo/generated/auth"
+	"github.com/Kotlang/authGo/otp"
+	"github.com/Kotlang/authGo/ratelimit"
+	"github.com/Kotlang/authGo/rbac"
+	"github.com/Kotlang/authGo/session"
+	"github.com/Kotlang/authGo/token"
 	"github.com/SaiNageswarS/go-api-boot/async"
 	"github.com/SaiNageswarS/go-api-boot/auth"
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
 	"github.com/jinzhu/copier"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -19,14 +35,36 @@ import (
 
 type LoginVerifiedService struct {
 	authPb.UnimplementedLoginVerifiedServer
-	mongo odm.MongoClient
+	tokenAuth
+	mongo        odm.MongoClient
+	ccfg         *appconfig.AppConfig
+	limiter      *ratelimit.Limiter
+	testAccounts *otp.TestAccounts
+	sessions     *session.Store
+	authz        *rbac.Authorizer
+	deletions    *deletion.Fanout
+	exporter     *dataexport.Exporter
 }
 
 func ProvideLoginVerifiedService(
-	mongo odm.MongoClient) *LoginVerifiedService {
+	mongo odm.MongoClient,
+	ccfg *appconfig.AppConfig,
+	sessions *session.Store,
+	keys *token.KeyStore,
+	authz *rbac.Authorizer,
+	deletions *deletion.Fanout,
+	exporter *dataexport.Exporter) *LoginVerifiedService {
 
 	return &LoginVerifiedService{
-		mongo: mongo,
+		tokenAuth:    tokenAuth{keys: keys},
+		mongo:        mongo,
+		ccfg:         ccfg,
+		limiter:      ratelimit.ProvideLimiter(mongo),
+		testAccounts: otp.ProvideTestAccounts(mongo),
+		sessions:     sessions,
+		authz:        authz,
+		deletions:    deletions,
+		exporter:     exporter,
 	}
 }
 
@@ -54,28 +92,23 @@ func (s *LoginVerifiedService) RequestProfileDeletion(ctx context.Context, req *
 }
 
 // Admin only API
-// CancelProfileDeletionRequest cancels profile deletion request and is used by admin only.
+// CancelProfileDeletionRequest cancels profile deletion request of the user and is used by admin only.
 func (s *LoginVerifiedService) CancelProfileDeletionRequest(ctx context.Context, req *authPb.IdRequest) (*authPb.StatusResponse, error) {
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
-	// Check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		return nil, status.Error(codes.PermissionDenied, "User with id "+userId+" don't have permission")
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "User id is required")
 	}
 
-	// Fetch profile info
-	loginRes, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).FindOneByID(ctx, userId))
+	// accounts whose purge has started are not restored.
+	restored, err := db.RestoreLogin(ctx, s.mongo, tenant, req.UserId)
 	if err != nil {
-		logger.Error("Failed saving profile deletion request", zap.Error(err))
-		return nil, status.Error(codes.Internal, "Failed saving profile deletion request")
+		logger.Error("Failed cancelling profile deletion request", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed cancelling profile deletion request")
 	}
-
-	loginRes.DeletionInfo = db.DeletionInfo{
-		MarkedForDeletion: false,
-		DeletionTime:      0,
-		Reason:            "",
+	if !restored {
+		return nil, status.Error(codes.FailedPrecondition, "User does not exist or is already being deleted")
 	}
-	async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).Save(ctx, *loginRes))
 
 	return &authPb.StatusResponse{
 		Status: "Profile deletion request cancelled successfully",
@@ -85,12 +118,7 @@ func (s *LoginVerifiedService) CancelProfileDeletionRequest(ctx context.Context,
 // Admin only API
 // GetPendingProfileDeletionRequests returns all profiles marked for deletion and is used by admin only.
 func (s *LoginVerifiedService) GetPendingProfileDeletionRequests(ctx context.Context, req *authPb.GetProfileDeletionRequest) (*authPb.ProfileListResponse, error) {
-	userID, tenant := auth.GetUserIdAndTenant(ctx)
-
-	// Check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userID) {
-		return nil, status.Error(codes.PermissionDenied, "User with id "+userID+" don't have permission")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
 	// Fetch pending profile deletion requests
 	filter := bson.M{
@@ -151,12 +179,7 @@ func (s *LoginVerifiedService) GetPendingProfileDeletionRequests(ctx context.Con
 // Admin only API
 // DeleteProfile deletes profile and login from db and is used by admin only.
 func (s *LoginVerifiedService) DeleteProfile(ctx context.Context, req *authPb.IdRequest) (*authPb.StatusResponse, error) {
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
-
-	// Check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		return nil, status.Error(codes.PermissionDenied, "User with id "+userId+" don't have permission")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
 	// Check if profile exists
 	isExists, _ := async.Await(odm.CollectionOf[db.ProfileModel](s.mongo, tenant).Exists(ctx, req.UserId))
@@ -167,10 +190,19 @@ func (s *LoginVerifiedService) DeleteProfile(ctx context.Context, req *authPb.Id
 		}, nil
 	}
 
+	// other services delete posts, comments, notifications etc. of the user on the user.deleted event.
+	// It is saved before anything is deleted so that it cannot be lost, and published once the login is gone.
+	event, err := s.deletions.Record(ctx, tenant, req.UserId, db.DeletionSourceAdmin)
+	if err != nil {
+		logger.Error("Failed recording deletion event", zap.String("userId", req.UserId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed deleting profile")
+	}
+
 	// Delete profile from db
-	_, err := async.Await(odm.CollectionOf[db.ProfileModel](s.mongo, tenant).DeleteByID(ctx, req.UserId))
+	_, err = async.Await(odm.CollectionOf[db.ProfileModel](s.mongo, tenant).DeleteByID(ctx, req.UserId))
 	if err != nil {
 		logger.Error("Failed deleting profile", zap.Error(err))
+		s.deletions.Cancel(ctx, tenant, event.EventId, "deleting profile failed")
 		return nil, status.Error(codes.Internal, "Failed deleting profile")
 	}
 
@@ -178,10 +210,16 @@ func (s *LoginVerifiedService) DeleteProfile(ctx context.Context, req *authPb.Id
 	_, err = async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).DeleteByID(ctx, req.UserId))
 	if err != nil {
 		logger.Error("Failed deleting login", zap.Error(err))
+		s.deletions.Cancel(ctx, tenant, event.EventId, "deleting login failed")
 		return nil, status.Error(codes.Internal, "Failed deleting login")
 	}
+	s.deletions.Publish(tenant, *event)
 
-	// TODO: Delete all posts, comments, notifications, etc. related to this user.
+	// tokens of deleted user are rejected as login is gone, refresh tokens are revoked too.
+	_, err = s.sessions.RevokeAll(ctx, tenant, req.UserId, session.RevokeReasonAdmin)
+	if err != nil {
+		logger.Error("Failed revoking sessions", zap.String("userId", req.UserId), zap.Error(err))
+	}
 
 	return &authPb.StatusResponse{
 		Status: "Profile deleted successfully",
@@ -189,21 +227,62 @@ func (s *LoginVerifiedService) DeleteProfile(ctx context.Context, req *authPb.Id
 }
 
 // Admin only API
+// GetDeletionStatus returns how far downstream services are with removing data of a deleted user.
+// Looks up the event by id, or the latest deletion of the user if no event id is given.
+func (s *LoginVerifiedService) GetDeletionStatus(ctx context.Context, req *authPb.DeletionStatusRequest) (*authPb.DeletionStatusResponse, error) {
+	_, tenant := auth.GetUserIdAndTenant(ctx)
+
+	if req.UserId == "" && req.EventId == "" {
+		return nil, status.Error(codes.InvalidArgument, "User id or event id is required")
+	}
+
+	event, err := s.deletions.Status(ctx, tenant, req.UserId, req.EventId)
+	if err != nil {
+		logger.Error("Failed getting deletion status", zap.String("userId", req.UserId), zap.String("eventId", req.EventId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed getting deletion status")
+	}
+	if event == nil {
+		return nil, status.Error(codes.NotFound, "Deletion not found")
+	}
+
+	res := &authPb.DeletionStatusResponse{
+		EventId:     event.EventId,
+		UserId:      event.UserId,
+		Source:      event.Source,
+		CreatedOn:   event.CreatedOn,
+		PublishedOn: event.PublishedOn,
+		Complete:    event.IsComplete(),
+	}
+	for _, service := range slices.Sorted(maps.Keys(event.Acks)) {
+		ack := event.Acks[service]
+		res.Services = append(res.Services, &authPb.ServiceDeletionStatus{
+			Service:        service,
+			Status:         ack.Status,
+			Details:        ack.Details,
+			AcknowledgedOn: ack.AcknowledgedOn,
+		})
+	}
+	return res, nil
+}
+
 // check if user is admin or not and return response.
 func (s *LoginVerifiedService) IsUserAdmin(ctx context.Context, req *authPb.IdRequest) (*authPb.IsUserAdminResponse, error) {
 	userId, tenant := auth.GetUserIdAndTenant(ctx)
 
-	if len(req.UserId) > 0 {
+	// admin status of other users is only visible to those who can read users.
+	if len(req.UserId) > 0 && req.UserId != userId {
+		if err := s.authz.Check(ctx, tenant, userId, rbac.PermUsersRead); err != nil {
+			return nil, err
+		}
 		userId = req.UserId
 	}
 
-	// Check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		return nil, status.Error(codes.PermissionDenied, "User with id "+userId+" don't have permission")
+	isAdmin, err := s.authz.HasRole(ctx, tenant, userId, rbac.RoleAdmin)
+	if err != nil {
+		logger.Error("Failed getting roles", zap.String("userId", userId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed getting roles")
 	}
 
-	isAdmin := db.IsAdmin(s.mongo, tenant, userId)
-
 	return &authPb.IsUserAdminResponse{
 		IsAdmin: isAdmin,
 	}, nil
@@ -213,17 +292,28 @@ func (s *LoginVerifiedService) IsUserAdmin(ctx context.Context, req *authPb.IdRe
 func (s *LoginVerifiedService) ChangeUserType(ctx context.Context, req *authPb.ChangeUserTypeRequest) (*authPb.StatusResponse, error) {
 	userId, tenant := auth.GetUserIdAndTenant(ctx)
 
-	// Check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		return nil, status.Error(codes.PermissionDenied, "User with id "+userId+" don't have permission")
+	phone := req.Phone
+	if len(phone) > 0 {
+		normalized, err := normalizeEmailOrPhone(s.ccfg, tenant, phone)
+		if err != nil {
+			return nil, err
+		}
+		phone = normalized
 	}
 
 	// fetch login info
-	loginModel := <-db.FindOneByPhoneOrEmail(ctx, s.mongo, tenant, req.Phone, req.Email)
+	loginModel := <-db.FindOneByPhoneOrEmail(ctx, s.mongo, tenant, phone, req.Email)
 	if loginModel == nil {
 		return nil, status.Error(codes.NotFound, "User not found")
 	}
 
+	// user type admin holds the admin role, so only admins can make or unmake admins.
+	if req.UserType.String() == rbac.AdminUserType || loginModel.UserType == rbac.AdminUserType {
+		if err := s.authz.CheckAdmin(ctx, tenant, userId); err != nil {
+			return nil, err
+		}
+	}
+
 	// change user type
 	loginModel.UserType = req.UserType.String()
 
@@ -234,40 +324,429 @@ func (s *LoginVerifiedService) ChangeUserType(ctx context.Context, req *authPb.C
 		return nil, status.Error(codes.Internal, "Failed changing user type")
 	}
 
+	// tokens with the old user type are rejected, sessions are kept so that clients can refresh.
+	err = db.BumpTokenEpoch(ctx, s.mongo, tenant, loginModel.Id())
+	if err != nil {
+		logger.Error("Failed invalidating tokens", zap.String("userId", loginModel.Id()), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed invalidating tokens")
+	}
+
 	return &authPb.StatusResponse{
 		Status: "User type changed successfully",
 	}, nil
 }
 
 // Admin only API
-// BlockUser blocks user.
-func (s *LoginVerifiedService) BlockUser(ctx context.Context, req *authPb.IdRequest) (*authPb.StatusResponse, error) {
+// BlockUser blocks the user until unblocked.
+func (s *LoginVerifiedService) BlockUser(ctx context.Context, req *authPb.BlockUserRequest) (*authPb.StatusResponse, error) {
+	err := s.blockUser(ctx, req.UserId, req.Reason, 0, db.AuditUserBlocked)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authPb.StatusResponse{
+		Status: "User blocked successfully",
+	}, nil
+}
+
+// Admin only API
+// SuspendUser blocks the user until expiresOn, after which the suspension lifts by itself.
+func (s *LoginVerifiedService) SuspendUser(ctx context.Context, req *authPb.SuspendUserRequest) (*authPb.StatusResponse, error) {
+	if req.ExpiresOn <= time.Now().Unix() {
+		return nil, status.Error(codes.InvalidArgument, "Expiry should be in future")
+	}
+
+	err := s.blockUser(ctx, req.UserId, req.Reason, req.ExpiresOn, db.AuditUserSuspended)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authPb.StatusResponse{
+		Status: "User suspended successfully",
+	}, nil
+}
+
+// Admin only API
+// UnblockUser lifts a block or suspension of the user.
+func (s *LoginVerifiedService) UnblockUser(ctx context.Context, req *authPb.UnblockUserRequest) (*authPb.StatusResponse, error) {
 	userId, tenant := auth.GetUserIdAndTenant(ctx)
 
-	// Check if user is admin
-	if !db.IsAdmin(s.mongo, tenant, userId) {
-		return nil, status.Error(codes.PermissionDenied, "User with id "+userId+" don't have permission")
+	found, err := db.UnblockLogin(ctx, s.mongo, tenant, req.UserId)
+	if err != nil {
+		logger.Error("Failed unblocking user", zap.String("userId", req.UserId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed unblocking user")
+	}
+	if !found {
+		return nil, status.Error(codes.NotFound, "User not found")
 	}
 
-	// fetch login info
-	loginRes, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).FindOneByID(ctx, userId))
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditUserUnblocked,
+		ActorId: userId,
+		Target:  req.UserId,
+		Details: map[string]string{"reason": req.Reason},
+	})
+	return &authPb.StatusResponse{
+		Status: "User unblocked successfully",
+	}, nil
+}
+
+// blocks the target user and ends their sessions. expiresOn is 0 for blocks lasting until unblocked.
+func (s *LoginVerifiedService) blockUser(ctx context.Context, targetUserId, reason string, expiresOn int64, auditAction string) error {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	reason = strings.TrimSpace(reason)
+	if targetUserId == "" || reason == "" {
+		return status.Error(codes.InvalidArgument, "User id and reason are required")
+	}
+	if targetUserId == userId {
+		return status.Error(codes.InvalidArgument, "Admins cannot block themselves")
+	}
+
+	target, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).FindOneByID(ctx, targetUserId))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return status.Error(codes.NotFound, "User not found")
+	}
 	if err != nil {
-		logger.Error("Failed getting login", zap.Error(err))
-		return nil, status.Error(codes.Internal, "Failed getting login")
+		logger.Error("Failed getting login", zap.String("userId", targetUserId), zap.Error(err))
+		return status.Error(codes.Internal, "Failed blocking user")
+	}
+	// blocking an admin takes admin rights, like changing their user type.
+	if target.UserType == rbac.AdminUserType {
+		if err := s.authz.CheckAdmin(ctx, tenant, userId); err != nil {
+			return err
+		}
 	}
 
-	loginRes.IsBlocked = true
-	_, err = async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).Save(ctx, *loginRes))
+	found, err := db.BlockLogin(ctx, s.mongo, tenant, targetUserId, db.BlockInfo{
+		Reason:    reason,
+		BlockedBy: userId,
+		BlockedOn: time.Now().Unix(),
+		ExpiresOn: expiresOn,
+	})
+	if err != nil {
+		logger.Error("Failed blocking user", zap.String("userId", targetUserId), zap.Error(err))
+		return status.Error(codes.Internal, "Failed blocking user")
+	}
+	if !found {
+		return status.Error(codes.NotFound, "User not found")
+	}
+
+	// existing tokens and sessions of the user stop working immediately.
+	err = db.BumpTokenEpoch(ctx, s.mongo, tenant, targetUserId)
 	if err != nil {
-		logger.Error("Failed blocking user", zap.Error(err))
-		return nil, status.Error(codes.Internal, "Failed blocking user")
+		logger.Error("Failed invalidating tokens", zap.String("userId", targetUserId), zap.Error(err))
+		return status.Error(codes.Internal, "Failed invalidating tokens")
 	}
 
+	_, err = s.sessions.RevokeAll(ctx, tenant, targetUserId, session.RevokeReasonAdmin)
+	if err != nil {
+		logger.Error("Failed revoking sessions", zap.String("userId", targetUserId), zap.Error(err))
+	}
+
+	details := map[string]string{"reason": reason}
+	if expiresOn > 0 {
+		details["expiresOn"] = strconv.FormatInt(expiresOn, 10)
+	}
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  auditAction,
+		ActorId: userId,
+		Target:  targetUserId,
+		Details: details,
+	})
+	return nil
+}
+
+// Admin only API
+// UnlockUser removes lockout of email/phone caused by failed otp verifications.
+func (s *LoginVerifiedService) UnlockUser(ctx context.Context, req *authPb.UnlockUserRequest) (*authPb.StatusResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	emailOrPhone, err := normalizeEmailOrPhone(s.ccfg, tenant, req.EmailOrPhone)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.limiter.Unlock(ctx, tenant, emailOrPhone)
+	if err != nil {
+		logger.Error("Failed unlocking user", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed unlocking user")
+	}
+
+	logger.Info("User unlocked", zap.String("emailOrPhone", emailOrPhone), zap.String("adminId", userId))
 	return &authPb.StatusResponse{
-		Status: "User blocked successfully",
+		Status: "User unlocked successfully",
+	}, nil
+}
+
+// Admin only API
+// SaveTestAccount creates or updates a test account which logs in with a static code.
+func (s *LoginVerifiedService) SaveTestAccount(ctx context.Context, req *authPb.SaveTestAccountRequest) (*authPb.TestAccountProto, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	emailOrPhone, err := normalizeEmailOrPhone(s.ccfg, tenant, req.EmailOrPhone)
+	if err != nil {
+		return nil, err
+	}
+	if emailOrPhone == "" {
+		return nil, status.Error(codes.InvalidArgument, "Email or phone is required")
+	}
+	if len(req.Code) < 6 {
+		return nil, status.Error(codes.InvalidArgument, "Code should have at least 6 characters")
+	}
+	if req.ExpiresOn <= time.Now().Unix() {
+		return nil, status.Error(codes.InvalidArgument, "Expiry should be in future")
+	}
+
+	userType := strings.TrimSpace(req.UserType)
+	if userType == "" {
+		userType = "member"
+	}
+	if userType == rbac.AdminUserType {
+		if err := s.authz.CheckAdmin(ctx, tenant, userId); err != nil {
+			return nil, err
+		}
+	}
+
+	// a test account for a real user's email or phone would let the caller log in as them.
+	logins, err := async.Await(db.FindLoginsOfIdentifier(ctx, s.mongo, tenant, emailOrPhone))
+	if err != nil {
+		logger.Error("Failed checking existing logins", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed saving test account")
+	}
+	for _, login := range logins {
+		if !login.IsTestAccount {
+			return nil, status.Error(codes.FailedPrecondition, "Email or phone belongs to an existing user")
+		}
+	}
+
+	account, err := s.testAccounts.Save(ctx, tenant, db.TestAccountModel{
+		Identifier:  emailOrPhone,
+		ExpiresOn:   req.ExpiresOn,
+		UserType:    userType,
+		Description: req.Description,
+		CreatedBy:   userId,
+	}, req.Code)
+	if err != nil {
+		logger.Error("Failed saving test account", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed saving test account")
+	}
+
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditTestAccountSaved,
+		ActorId: userId,
+		Target:  emailOrPhone,
+		Details: map[string]string{"userType": userType, "expiresOn": strconv.FormatInt(req.ExpiresOn, 10)},
+	})
+	return getTestAccountProto(account), nil
+}
+
+// Admin only API
+// GetTestAccounts lists test accounts of the tenant.
+func (s *LoginVerifiedService) GetTestAccounts(ctx context.Context, req *authPb.GetTestAccountsRequest) (*authPb.TestAccountListResponse, error) {
+	_, tenant := auth.GetUserIdAndTenant(ctx)
+
+	accounts, err := s.testAccounts.List(ctx, tenant)
+	if err != nil {
+		logger.Error("Failed getting test accounts", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed getting test accounts")
+	}
+
+	res := &authPb.TestAccountListResponse{}
+	for i := range accounts {
+		res.TestAccounts = append(res.TestAccounts, getTestAccountProto(&accounts[i]))
+	}
+	return res, nil
+}
+
+// Admin only API
+// DeleteTestAccount removes a test account.
+func (s *LoginVerifiedService) DeleteTestAccount(ctx context.Context, req *authPb.DeleteTestAccountRequest) (*authPb.StatusResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	emailOrPhone, err := normalizeEmailOrPhone(s.ccfg, tenant, req.EmailOrPhone)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.testAccounts.Delete(ctx, tenant, emailOrPhone)
+	if err != nil {
+		logger.Error("Failed deleting test account", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed deleting test account")
+	}
+
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditTestAccountDeleted,
+		ActorId: userId,
+		Target:  emailOrPhone,
+	})
+	return &authPb.StatusResponse{
+		Status: "Test account deleted successfully",
 	}, nil
 }
 
+// Logout revokes the session of the access token used for the call.
+func (s *LoginVerifiedService) Logout(ctx context.Context, req *authPb.LogoutRequest) (*authPb.StatusResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	claims, err := token.ClaimsFromContext(ctx)
+	if err != nil || claims.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token does not belong to a session")
+	}
+
+	_, err = s.sessions.Revoke(ctx, tenant, userId, claims.SessionId, session.RevokeReasonLogout)
+	if err != nil {
+		logger.Error("Failed revoking session", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed logging out")
+	}
+
+	return &authPb.StatusResponse{
+		Status: "Logged out successfully",
+	}, nil
+}
+
+// ListMySessions lists active sessions of the caller.
+func (s *LoginVerifiedService) ListMySessions(ctx context.Context, req *authPb.ListMySessionsRequest) (*authPb.SessionListResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	sessions, err := s.sessions.ListActive(ctx, tenant, userId)
+	if err != nil {
+		logger.Error("Failed getting sessions", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed getting sessions")
+	}
+
+	currentSessionId := ""
+	if claims, err := token.ClaimsFromContext(ctx); err == nil {
+		currentSessionId = claims.SessionId
+	}
+
+	res := &authPb.SessionListResponse{}
+	for _, userSession := range sessions {
+		res.Sessions = append(res.Sessions, &authPb.SessionProto{
+			SessionId:       userSession.SessionId,
+			UserAgent:       userSession.Device.UserAgent,
+			Ip:              userSession.Device.Ip,
+			CreatedOn:       userSession.CreatedOn,
+			LastRefreshedOn: userSession.LastRefreshedOn,
+			ExpiresOn:       userSession.ExpiresOn,
+			IsCurrent:       userSession.SessionId == currentSessionId,
+		})
+	}
+	return res, nil
+}
+
+// RevokeSession revokes a session of the caller.
+// Admins can revoke session of another user by passing user id.
+func (s *LoginVerifiedService) RevokeSession(ctx context.Context, req *authPb.RevokeSessionRequest) (*authPb.StatusResponse, error) {
+	targetUserId, tenant, reason, err := s.getSessionOwner(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.sessions.Revoke(ctx, tenant, targetUserId, req.SessionId, reason)
+	if err != nil {
+		logger.Error("Failed revoking session", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed revoking session")
+	}
+	if !revoked {
+		return nil, status.Error(codes.NotFound, "Session not found")
+	}
+
+	userId, _ := auth.GetUserIdAndTenant(ctx)
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditSessionRevoked,
+		ActorId: userId,
+		Target:  targetUserId,
+		Details: map[string]string{"sessionId": req.SessionId},
+	})
+	return &authPb.StatusResponse{
+		Status: "Session revoked successfully",
+	}, nil
+}
+
+// RevokeAllSessions revokes every session of the caller, including the current one.
+// Admins can revoke sessions of another user by passing user id.
+func (s *LoginVerifiedService) RevokeAllSessions(ctx context.Context, req *authPb.RevokeAllSessionsRequest) (*authPb.StatusResponse, error) {
+	targetUserId, tenant, reason, err := s.getSessionOwner(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.sessions.RevokeAll(ctx, tenant, targetUserId, reason)
+	if err != nil {
+		logger.Error("Failed revoking sessions", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed revoking sessions")
+	}
+
+	userId, _ := auth.GetUserIdAndTenant(ctx)
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditAllSessionsRevoked,
+		ActorId: userId,
+		Target:  targetUserId,
+		Details: map[string]string{"count": strconv.FormatInt(count, 10)},
+	})
+	return &authPb.StatusResponse{
+		Status: "Sessions revoked successfully",
+	}, nil
+}
+
+// ExportMyData packages the caller's personal data as an archive and returns a single use download link.
+func (s *LoginVerifiedService) ExportMyData(ctx context.Context, req *authPb.ExportMyDataRequest) (*authPb.DataExportResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	allowed, retryAfter, err := s.limiter.Allow(ctx, tenant, ratelimit.DataExportsPerUser, userId)
+	if err != nil {
+		logger.Error("Error checking rate limit", zap.String("limit", ratelimit.DataExportsPerUser.Name), zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "Failed checking rate limits")
+	}
+	if !allowed {
+		return nil, apierror.New(codes.ResourceExhausted, apierror.ReasonRateLimited,
+			fmt.Sprintf("Too many exports. Try again in %d seconds", int(retryAfter.Seconds())+1), retryAfter, map[string]string{"limit": ratelimit.DataExportsPerUser.Name})
+	}
+
+	export, downloadUrl, err := s.exporter.Create(ctx, tenant, userId)
+	if err != nil {
+		logger.Error("Failed exporting user data", zap.String("userId", userId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed exporting data")
+	}
+
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditDataExportCreated,
+		ActorId: userId,
+		Target:  export.ExportId,
+	})
+	return &authPb.DataExportResponse{
+		ExportId:    export.ExportId,
+		DownloadUrl: downloadUrl,
+		ExpiresOn:   export.ExpiresOn,
+	}, nil
+}
+
+// sessions of other users can only be revoked by users with sessions.revoke.
+func (s *LoginVerifiedService) getSessionOwner(ctx context.Context, targetUserId string) (string, string, string, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if targetUserId == "" || targetUserId == userId {
+		return userId, tenant, session.RevokeReasonUser, nil
+	}
+
+	if err := s.authz.Check(ctx, tenant, userId, rbac.PermSessionsRevoke); err != nil {
+		return "", "", "", err
+	}
+	return targetUserId, tenant, session.RevokeReasonAdmin, nil
+}
+
+// code is never returned.
+func getTestAccountProto(account *db.TestAccountModel) *authPb.TestAccountProto {
+	return &authPb.TestAccountProto{
+		EmailOrPhone: account.Identifier,
+		ExpiresOn:    account.ExpiresOn,
+		UserType:     account.UserType,
+		Description:  account.Description,
+		CreatedBy:    account.CreatedBy,
+		CreatedOn:    account.CreatedOn,
+	}
+}
+
 func populateLoginInfo(userProfileProto []*authPb.UserProfileProto, loginInfo []db.LoginModel) {
 	for i, profile := range userProfileProto {
 		for _, loginModel := range loginInfo {
@@ -0,0 +1,86 @@
+package service
+
+import (
+	authPb "github.com/Kotlang/authGo/generated/auth"
+	"github.com/Kotlang/authGo/rbac"
+)
+
+// MethodPolicies declares who can call each rpc. Every registered method must have an entry,
+// the server does not start otherwise.
+// Methods acting on the caller's own data or on other users depending on the request
+// are Authenticated here and check the permission in the handler.
+func MethodPolicies() rbac.MethodPolicies {
+	return rbac.MethodPolicies{}.
+		Add(authPb.Login_ServiceDesc, map[string]rbac.Policy{
+			"Login":        rbac.Public,
+			"Verify":       rbac.Public,
+			"RefreshToken": rbac.Public,
+		}).
+		// callers authenticate with client credentials.
+		Add(authPb.Introspection_ServiceDesc, map[string]rbac.Policy{
+			"IntrospectToken": rbac.Public,
+		}).
+		Add(authPb.DeletionAck_ServiceDesc, map[string]rbac.Policy{
+			"AcknowledgeDeletion": rbac.Public,
+		}).
+		Add(authPb.LoginVerified_ServiceDesc, map[string]rbac.Policy{
+			"RequestProfileDeletion":            rbac.Authenticated,
+			"CancelProfileDeletionRequest":      rbac.Require(rbac.PermUsersDelete),
+			"GetPendingProfileDeletionRequests": rbac.Require(rbac.PermUsersRead),
+			"DeleteProfile":                     rbac.Require(rbac.PermUsersDelete),
+			"GetDeletionStatus":                 rbac.Require(rbac.PermUsersRead),
+			"IsUserAdmin":                       rbac.Authenticated,
+			"ChangeUserType":                    rbac.Require(rbac.PermUsersChangeType),
+			"BlockUser":                         rbac.Require(rbac.PermUsersBlock),
+			"SuspendUser":                       rbac.Require(rbac.PermUsersBlock),
+			"UnblockUser":                       rbac.Require(rbac.PermUsersBlock),
+			"UnlockUser":                        rbac.Require(rbac.PermUsersBlock),
+			"SaveTestAccount":                   rbac.Require(rbac.PermTestAccountsManage),
+			"GetTestAccounts":                   rbac.Require(rbac.PermTestAccountsManage),
+			"DeleteTestAccount":                 rbac.Require(rbac.PermTestAccountsManage),
+			"Logout":                            rbac.Authenticated,
+			"ListMySessions":                    rbac.Authenticated,
+			"RevokeSession":                     rbac.Authenticated,
+			"RevokeAllSessions":                 rbac.Authenticated,
+			"ExportMyData":                      rbac.Authenticated,
+		}).
+		Add(authPb.Profile_ServiceDesc, map[string]rbac.Policy{
+			"CreateOrUpdateProfile":    rbac.Authenticated,
+			"GetProfileById":           rbac.Authenticated,
+			"BulkGetProfileByIds":      rbac.Authenticated,
+			"GetProfileImageUploadUrl": rbac.Authenticated,
+			"UploadProfileImage":       rbac.Authenticated,
+		}).
+		Add(authPb.ProfileMaster_ServiceDesc, map[string]rbac.Policy{
+			"GetProfileMaster":     rbac.Authenticated,
+			"GetLanguages":         rbac.Authenticated,
+			"BulkGetProfileMaster": rbac.Require(rbac.PermProfileMasterRead),
+			"AddProfileMaster":     rbac.Require(rbac.PermProfileMasterEdit),
+		}).
+		Add(authPb.LeadService_ServiceDesc, map[string]rbac.Policy{
+			"CreateLead":       rbac.Require(rbac.PermLeadsWrite),
+			"GetLeadById":      rbac.Require(rbac.PermLeadsRead),
+			"BulkGetLeadsById": rbac.Require(rbac.PermLeadsRead),
+			"UpdateLead":       rbac.Require(rbac.PermLeadsWrite),
+			"DeleteLead":       rbac.Require(rbac.PermLeadsWrite),
+			"FetchLeads":       rbac.Require(rbac.PermLeadsRead),
+		}).
+		Add(authPb.Role_ServiceDesc, map[string]rbac.Policy{
+			"SaveRole":           rbac.Require(rbac.PermRolesManage),
+			"ListRoles":          rbac.Require(rbac.PermRolesManage),
+			"DeleteRole":         rbac.Require(rbac.PermRolesManage),
+			"AssignRole":         rbac.Require(rbac.PermRolesManage),
+			"UnassignRole":       rbac.Require(rbac.PermRolesManage),
+			"GetUserPermissions": rbac.Authenticated,
+		}).
+		// handlers also check that the caller is in the platform tenant.
+		Add(authPb.Tenant_ServiceDesc, map[string]rbac.Policy{
+			"CreateTenant":       rbac.Require(rbac.PermTenantsManage),
+			"ListTenants":        rbac.Require(rbac.PermTenantsManage),
+			"SuspendTenant":      rbac.Require(rbac.PermTenantsManage),
+			"ResumeTenant":       rbac.Require(rbac.PermTenantsManage),
+			"DeleteTenant":       rbac.Require(rbac.PermTenantsManage),
+			"GetTenantPolicy":    rbac.Require(rbac.PermTenantsManage),
+			"UpdateTenantPolicy": rbac.Require(rbac.PermTenantsManage),
+		})
+}
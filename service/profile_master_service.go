@@ -7,6 +7,7 @@ import (
 
 	"github.com/Kotlang/authGo/db"
 	authPb "github.com/Kotlang/authGo/generated/auth"
+	"github.com/Kotlang/authGo/token"
 	"github.com/SaiNageswarS/go-api-boot/async"
 	"github.com/SaiNageswarS/go-api-boot/auth"
 	"github.com/SaiNageswarS/go-api-boot/logger"
@@ -20,12 +21,14 @@ import (
 
 type ProfileMasterService struct {
 	authPb.UnimplementedProfileMasterServer
+	tokenAuth
 	mongo odm.MongoClient
 }
 
-func ProvideProfileMasterService(mongo odm.MongoClient) *ProfileMasterService {
+func ProvideProfileMasterService(mongo odm.MongoClient, keys *token.KeyStore) *ProfileMasterService {
 	return &ProfileMasterService{
-		mongo: mongo,
+		tokenAuth: tokenAuth{keys: keys},
+		mongo:     mongo,
 	}
 }
 
@@ -73,17 +76,7 @@ func (s *ProfileMasterService) GetLanguages(ctx context.Context, req *authPb.Get
 
 // ADMIN PORTAL API
 func (s *ProfileMasterService) BulkGetProfileMaster(ctx context.Context, req *authPb.BulkGetProfileMasterRequest) (*authPb.ProfileMasterResponse, error) {
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
-
-	loginModel, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).FindOneByID(ctx, userId))
-	if err != nil {
-		logger.Error("Failed getting login info using id: "+userId, zap.Error(err))
-		return nil, status.Error(codes.Internal, "Failed getting login info using id: "+userId)
-	}
-
-	if loginModel.UserType != "admin" {
-		return nil, status.Error(codes.PermissionDenied, "User with id"+userId+" don't have permission")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
 	profileMasterList, err := async.Await(odm.CollectionOf[db.ProfileMasterModel](s.mongo, tenant).Find(ctx, bson.M{}, nil, 0, 0))
 	if err != nil {
@@ -101,17 +94,7 @@ func (s *ProfileMasterService) BulkGetProfileMaster(ctx context.Context, req *au
 // ADMIN PORTAL API
 // Add Profile Master
 func (s *ProfileMasterService) AddProfileMaster(ctx context.Context, req *authPb.AddProfileMasterRequest) (*authPb.ProfileMasterProto, error) {
-	userId, tenant := auth.GetUserIdAndTenant(ctx)
-
-	loginModel, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).FindOneByID(ctx, userId))
-	if err != nil {
-		logger.Error("Failed getting login info using id: "+userId, zap.Error(err))
-		return nil, status.Error(codes.Internal, "Failed getting login info using id: "+userId)
-	}
-
-	if loginModel.UserType != "admin" {
-		return nil, status.Error(codes.PermissionDenied, "User with id"+userId+" don't have permission")
-	}
+	_, tenant := auth.GetUserIdAndTenant(ctx)
 
 	if len(strings.TrimSpace(req.Language)) == 0 {
 		logger.Error("Language is not present")
@@ -120,7 +103,7 @@ func (s *ProfileMasterService) AddProfileMaster(ctx context.Context, req *authPb
 	profileMaster := &db.ProfileMasterModel{}
 	copier.CopyWithOption(profileMaster, req, copier.Option{IgnoreEmpty: true, DeepCopy: true})
 
-	_, err = async.Await(odm.CollectionOf[db.ProfileMasterModel](s.mongo, tenant).Save(ctx, *profileMaster))
+	_, err := async.Await(odm.CollectionOf[db.ProfileMasterModel](s.mongo, tenant).Save(ctx, *profileMaster))
 
 	if err != nil {
 		logger.Error("Internal error when saving Profile Master with id: "+profileMaster.Id(), zap.Error(err))
@@ -12,6 +12,7 @@ import (
 	"github.com/Kotlang/authGo/extensions"
 	authPb "github.com/Kotlang/authGo/generated/auth"
 	notificationPb "github.com/Kotlang/authGo/generated/notification"
+	"github.com/Kotlang/authGo/token"
 	"github.com/SaiNageswarS/go-api-boot/async"
 	"github.com/SaiNageswarS/go-api-boot/auth"
 	"github.com/SaiNageswarS/go-api-boot/cloud"
@@ -28,16 +29,18 @@ import (
 
 type ProfileService struct {
 	authPb.UnimplementedProfileServer
+	tokenAuth
 	ccfg     *appconfig.AppConfig
 	mongo    odm.MongoClient
 	cloudFns cloud.Cloud
 }
 
-func ProvideProfileService(mongo odm.MongoClient, cloudFns cloud.Cloud, ccfg *appconfig.AppConfig) *ProfileService {
+func ProvideProfileService(mongo odm.MongoClient, cloudFns cloud.Cloud, ccfg *appconfig.AppConfig, keys *token.KeyStore) *ProfileService {
 	return &ProfileService{
-		mongo:    mongo,
-		cloudFns: cloudFns,
-		ccfg:     ccfg,
+		tokenAuth: tokenAuth{keys: keys},
+		mongo:     mongo,
+		cloudFns:  cloudFns,
+		ccfg:      ccfg,
 	}
 }
 
@@ -128,8 +131,9 @@ func (s *ProfileService) BulkGetProfileByIds(ctx context.Context, req *authPb.Bu
 
 	// profile info
 	userIds := []string{}
+	now := time.Now().Unix()
 	for _, login := range loginInfo {
-		if !login.DeletionInfo.MarkedForDeletion && !login.IsBlocked {
+		if !login.IsDeleting() && !login.IsBlockedAt(now) {
 			userIds = append(userIds, login.UserId)
 		}
 	}
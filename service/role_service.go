@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Kotlang/authGo/db"
+	authPb "github.com/Kotlang/authGo/generated/auth"
+	"github.com/Kotlang/authGo/rbac"
+	"github.com/Kotlang/authGo/token"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RoleService manages roles of a tenant and their assignment to users.
+type RoleService struct {
+	authPb.UnimplementedRoleServer
+	tokenAuth
+	mongo odm.MongoClient
+	authz *rbac.Authorizer
+}
+
+func ProvideRoleService(mongo odm.MongoClient, keys *token.KeyStore, authz *rbac.Authorizer) *RoleService {
+	return &RoleService{tokenAuth: tokenAuth{keys: keys}, mongo: mongo, authz: authz}
+}
+
+// Admin only API
+// SaveRole creates or replaces a role. The built in admin role cannot be changed.
+func (s *RoleService) SaveRole(ctx context.Context, req *authPb.SaveRoleRequest) (*authPb.RoleProto, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	name := strings.TrimSpace(req.Name)
+	if !rbac.IsValidRoleName(name) {
+		return nil, status.Error(codes.InvalidArgument, "Role name should be 2-64 letters, digits, _ . or -")
+	}
+	if name == rbac.RoleAdmin {
+		return nil, status.Error(codes.InvalidArgument, "Built in role "+rbac.RoleAdmin+" cannot be changed")
+	}
+
+	permissions := []string{}
+	for _, permission := range req.Permissions {
+		if !rbac.IsValidPermission(permission) {
+			return nil, status.Error(codes.InvalidArgument, "Unknown permission "+permission)
+		}
+		permissions = append(permissions, permission)
+	}
+	if err := s.authz.CheckGrant(ctx, tenant, userId, permissions); err != nil {
+		return nil, err
+	}
+
+	role := db.RoleModel{
+		Name:        name,
+		Description: req.Description,
+		Permissions: permissions,
+		CreatedBy:   userId,
+	}
+	_, err := async.Await(odm.CollectionOf[db.RoleModel](s.mongo, tenant).Save(ctx, role))
+	if err != nil {
+		logger.Error("Failed saving role", zap.String("role", name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed saving role")
+	}
+
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditRoleSaved,
+		ActorId: userId,
+		Target:  name,
+		Details: map[string]string{"permissions": strings.Join(permissions, ",")},
+	})
+	return getRoleProto(&role), nil
+}
+
+// Admin only API
+func (s *RoleService) ListRoles(ctx context.Context, req *authPb.ListRolesRequest) (*authPb.RoleListResponse, error) {
+	_, tenant := auth.GetUserIdAndTenant(ctx)
+
+	roles, err := s.authz.ListRoles(ctx, tenant)
+	if err != nil {
+		logger.Error("Failed getting roles", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed getting roles")
+	}
+
+	res := &authPb.RoleListResponse{}
+	for i := range roles {
+		res.Roles = append(res.Roles, getRoleProto(&roles[i]))
+	}
+	return res, nil
+}
+
+// Admin only API
+// DeleteRole removes the role and its assignments.
+func (s *RoleService) DeleteRole(ctx context.Context, req *authPb.RoleNameRequest) (*authPb.StatusResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	if req.Name == rbac.RoleAdmin {
+		return nil, status.Error(codes.InvalidArgument, "Built in role "+rbac.RoleAdmin+" cannot be deleted")
+	}
+
+	_, err := async.Await(odm.CollectionOf[db.RoleModel](s.mongo, tenant).FindOneByID(ctx, req.Name))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, status.Error(codes.NotFound, "Role not found")
+	}
+	if err != nil {
+		logger.Error("Failed getting role", zap.String("role", req.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed deleting role")
+	}
+
+	if err := db.UnassignRoleFromAll(ctx, s.mongo, tenant, req.Name); err != nil {
+		logger.Error("Failed removing role assignments", zap.String("role", req.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed deleting role")
+	}
+
+	_, err = async.Await(odm.CollectionOf[db.RoleModel](s.mongo, tenant).DeleteByID(ctx, req.Name))
+	if err != nil {
+		logger.Error("Failed deleting role", zap.String("role", req.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed deleting role")
+	}
+
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditRoleDeleted,
+		ActorId: userId,
+		Target:  req.Name,
+	})
+	return &authPb.StatusResponse{Status: "Role deleted successfully"}, nil
+}
+
+// Admin only API
+func (s *RoleService) AssignRole(ctx context.Context, req *authPb.RoleAssignmentRequest) (*authPb.StatusResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	// the caller can only assign roles granting no more than they have.
+	if req.Role == rbac.RoleAdmin {
+		if err := s.authz.CheckAdmin(ctx, tenant, userId); err != nil {
+			return nil, err
+		}
+	} else {
+		role, err := async.Await(odm.CollectionOf[db.RoleModel](s.mongo, tenant).FindOneByID(ctx, req.Role))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, status.Error(codes.NotFound, "Role not found")
+		}
+		if err != nil {
+			logger.Error("Failed getting role", zap.String("role", req.Role), zap.Error(err))
+			return nil, status.Error(codes.Internal, "Failed assigning role")
+		}
+		if err := s.authz.CheckGrant(ctx, tenant, userId, role.Permissions); err != nil {
+			return nil, err
+		}
+	}
+
+	found, err := db.AssignRole(ctx, s.mongo, tenant, req.UserId, req.Role)
+	if err != nil {
+		logger.Error("Failed assigning role", zap.String("userId", req.UserId), zap.String("role", req.Role), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed assigning role")
+	}
+	if !found {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditRoleAssigned,
+		ActorId: userId,
+		Target:  req.UserId,
+		Details: map[string]string{"role": req.Role},
+	})
+	return &authPb.StatusResponse{Status: "Role assigned successfully"}, nil
+}
+
+// Admin only API
+// UnassignRole removes a role from the user. Admin role held through user type admin
+// is removed by changing the user type.
+func (s *RoleService) UnassignRole(ctx context.Context, req *authPb.RoleAssignmentRequest) (*authPb.StatusResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	found, err := db.UnassignRole(ctx, s.mongo, tenant, req.UserId, req.Role)
+	if err != nil {
+		logger.Error("Failed removing role", zap.String("userId", req.UserId), zap.String("role", req.Role), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed removing role")
+	}
+	if !found {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	db.SaveAuditLog(ctx, s.mongo, tenant, db.AuditLogModel{
+		Action:  db.AuditRoleUnassigned,
+		ActorId: userId,
+		Target:  req.UserId,
+		Details: map[string]string{"role": req.Role},
+	})
+	return &authPb.StatusResponse{Status: "Role removed successfully"}, nil
+}
+
+// GetUserPermissions returns roles and permissions of the caller.
+// Permissions of other users can only be read by admins.
+func (s *RoleService) GetUserPermissions(ctx context.Context, req *authPb.IdRequest) (*authPb.UserPermissionsResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if req.UserId != "" && req.UserId != userId {
+		if err := s.authz.Check(ctx, tenant, userId, rbac.PermUsersRead); err != nil {
+			return nil, err
+		}
+		userId = req.UserId
+	}
+
+	roles, err := s.authz.Roles(ctx, tenant, userId)
+	if err != nil {
+		logger.Error("Failed getting roles", zap.String("userId", userId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed getting permissions")
+	}
+
+	permissions, err := s.authz.Permissions(ctx, tenant, userId)
+	if err != nil {
+		logger.Error("Failed getting permissions", zap.String("userId", userId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed getting permissions")
+	}
+
+	return &authPb.UserPermissionsResponse{
+		UserId:      userId,
+		Roles:       roles,
+		Permissions: permissions,
+	}, nil
+}
+
+func getRoleProto(role *db.RoleModel) *authPb.RoleProto {
+	return &authPb.RoleProto{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.Permissions,
+		BuiltIn:     role.Name == rbac.RoleAdmin,
+		CreatedBy:   role.CreatedBy,
+		CreatedOn:   role.CreatedOn,
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Kotlang/authGo/appconfig"
+	"github.com/Kotlang/authGo/db"
+	authPb "github.com/Kotlang/authGo/generated/auth"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/Kotlang/authGo/token"
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TenantService manages the tenant registry. Only users of the platform tenant with tenants.manage can use it.
+type TenantService struct {
+	authPb.UnimplementedTenantServer
+	tokenAuth
+	mongo    odm.MongoClient
+	ccfg     *appconfig.AppConfig
+	registry *tenant.Registry
+}
+
+func ProvideTenantService(mongo odm.MongoClient, ccfg *appconfig.AppConfig, keys *token.KeyStore, registry *tenant.Registry) *TenantService {
+	return &TenantService{
+		tokenAuth: tokenAuth{keys: keys},
+		mongo:     mongo,
+		ccfg:      ccfg,
+		registry:  registry,
+	}
+}
+
+// Platform admin only API
+func (s *TenantService) CreateTenant(ctx context.Context, req *authPb.CreateTenantRequest) (*authPb.TenantProto, error) {
+	userId, err := s.checkPlatformAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if !tenant.IsValidName(name) {
+		return nil, status.Error(codes.InvalidArgument, "Tenant name should be 2-38 lowercase letters, digits, - or _")
+	}
+
+	created, err := s.registry.Create(ctx, db.TenantModel{
+		Name:        name,
+		DisplayName: req.DisplayName,
+		Settings:    req.Settings,
+		CreatedBy:   userId,
+	})
+	if errors.Is(err, tenant.ErrTenantExists) {
+		return nil, status.Error(codes.AlreadyExists, "Tenant already exists")
+	}
+	if errors.Is(err, tenant.ErrTenantNameReserved) {
+		return nil, status.Error(codes.InvalidArgument, "Tenant name is reserved")
+	}
+	if err != nil {
+		logger.Error("Failed creating tenant", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed creating tenant")
+	}
+
+	s.audit(ctx, db.AuditTenantCreated, userId, name, map[string]string{"displayName": req.DisplayName})
+	return getTenantProto(created), nil
+}
+
+// Platform admin only API
+func (s *TenantService) ListTenants(ctx context.Context, req *authPb.ListTenantsRequest) (*authPb.TenantListResponse, error) {
+	if _, err := s.checkPlatformAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	tenants, err := s.registry.List(ctx)
+	if err != nil {
+		logger.Error("Failed getting tenants", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed getting tenants")
+	}
+
+	res := &authPb.TenantListResponse{}
+	for i := range tenants {
+		res.Tenants = append(res.Tenants, getTenantProto(&tenants[i]))
+	}
+	return res, nil
+}
+
+// Platform admin only API
+// SuspendTenant rejects logins and calls of the tenant's users until it is resumed.
+func (s *TenantService) SuspendTenant(ctx context.Context, req *authPb.TenantNameRequest) (*authPb.TenantProto, error) {
+	return s.setStatus(ctx, req.Name, db.TenantSuspended)
+}
+
+// Platform admin only API
+func (s *TenantService) ResumeTenant(ctx context.Context, req *authPb.TenantNameRequest) (*authPb.TenantProto, error) {
+	return s.setStatus(ctx, req.Name, db.TenantActive)
+}
+
+// Platform admin only API
+// DeleteTenant unregisters the tenant. Its database is kept and its name cannot be reused.
+func (s *TenantService) DeleteTenant(ctx context.Context, req *authPb.TenantNameRequest) (*authPb.TenantProto, error) {
+	return s.setStatus(ctx, req.Name, db.TenantDeleted)
+}
+
+// Platform admin only API
+// GetTenantPolicy returns the authentication policy in effect for the tenant, defaults included.
+func (s *TenantService) GetTenantPolicy(ctx context.Context, req *authPb.TenantNameRequest) (*authPb.TenantPolicyProto, error) {
+	if _, err := s.checkPlatformAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	registered, err := s.registry.Get(ctx, req.Name)
+	if err != nil {
+		logger.Error("Failed getting tenant", zap.String("tenant", req.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed getting tenant")
+	}
+	if registered == nil {
+		return nil, status.Error(codes.NotFound, "Tenant not found")
+	}
+
+	return getTenantPolicyProto(registered.Policy.WithDefaults()), nil
+}
+
+// Platform admin only API
+// UpdateTenantPolicy replaces the tenant's authentication policy. Unset values take defaults.
+func (s *TenantService) UpdateTenantPolicy(ctx context.Context, req *authPb.UpdateTenantPolicyRequest) (*authPb.TenantPolicyProto, error) {
+	userId, err := s.checkPlatformAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := db.TenantPolicy{}
+	if req.Policy != nil {
+		policy = db.TenantPolicy{
+			Registration:          strings.TrimSpace(req.Policy.Registration),
+			AllowedIdentifiers:    req.Policy.AllowedIdentifiers,
+			OtpLength:             int(req.Policy.OtpLength),
+			OtpTtlSeconds:         int(req.Policy.OtpTtlSeconds),
+			ResendIntervalSeconds: int(req.Policy.ResendIntervalSeconds),
+			DailyOtpCap:           int(req.Policy.DailyOtpCap),
+			SessionLifetimeHours:  int(req.Policy.SessionLifetimeHours),
+			DefaultUserType:       strings.TrimSpace(req.Policy.DefaultUserType),
+		}
+	}
+	if err := tenant.ValidatePolicy(policy); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	updated, err := s.registry.SetPolicy(ctx, req.Name, policy)
+	if err != nil {
+		logger.Error("Failed updating tenant policy", zap.String("tenant", req.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed updating tenant")
+	}
+	if updated == nil {
+		return nil, status.Error(codes.NotFound, "Tenant not found")
+	}
+
+	effective := updated.Policy.WithDefaults()
+	s.audit(ctx, db.AuditTenantPolicyChanged, userId, req.Name, map[string]string{
+		"registration":       effective.Registration,
+		"allowedIdentifiers": strings.Join(effective.AllowedIdentifiers, ","),
+		"defaultUserType":    effective.DefaultUserType,
+	})
+	return getTenantPolicyProto(effective), nil
+}
+
+func (s *TenantService) setStatus(ctx context.Context, name, tenantStatus string) (*authPb.TenantProto, error) {
+	userId, err := s.checkPlatformAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == s.ccfg.PlatformTenant {
+		return nil, status.Error(codes.InvalidArgument, "Platform tenant cannot be changed")
+	}
+
+	updated, err := s.registry.SetStatus(ctx, name, tenantStatus)
+	if err != nil {
+		logger.Error("Failed updating tenant status", zap.String("tenant", name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed updating tenant")
+	}
+	if updated == nil {
+		return nil, status.Error(codes.NotFound, "Tenant not found")
+	}
+
+	s.audit(ctx, db.AuditTenantStatusChanged, userId, name, map[string]string{"status": tenantStatus})
+	return getTenantProto(updated), nil
+}
+
+// tenants.manage is checked by the authorization interceptor, it only counts in the platform tenant.
+func (s *TenantService) checkPlatformAdmin(ctx context.Context) (string, error) {
+	userId, callerTenant := auth.GetUserIdAndTenant(ctx)
+	if s.ccfg.PlatformTenant == "" || callerTenant != s.ccfg.PlatformTenant {
+		return "", status.Error(codes.PermissionDenied, "User with id "+userId+" don't have permission")
+	}
+	return userId, nil
+}
+
+// tenant changes are audited in the platform tenant.
+func (s *TenantService) audit(ctx context.Context, action, userId, target string, details map[string]string) {
+	db.SaveAuditLog(ctx, s.mongo, s.ccfg.PlatformTenant, db.AuditLogModel{
+		Action:  action,
+		ActorId: userId,
+		Target:  target,
+		Details: details,
+	})
+}
+
+func getTenantProto(tenant *db.TenantModel) *authPb.TenantProto {
+	return &authPb.TenantProto{
+		Name:        tenant.Name,
+		DisplayName: tenant.DisplayName,
+		Status:      tenant.Status,
+		Settings:    tenant.Settings,
+		CreatedBy:   tenant.CreatedBy,
+		CreatedOn:   tenant.CreatedOn,
+	}
+}
+
+func getTenantPolicyProto(policy db.TenantPolicy) *authPb.TenantPolicyProto {
+	return &authPb.TenantPolicyProto{
+		Registration:          policy.Registration,
+		AllowedIdentifiers:    policy.AllowedIdentifiers,
+		OtpLength:             int32(policy.OtpLength),
+		OtpTtlSeconds:         int32(policy.OtpTtlSeconds),
+		ResendIntervalSeconds: int32(policy.ResendIntervalSeconds),
+		DailyOtpCap:           int32(policy.DailyOtpCap),
+		SessionLifetimeHours:  int32(policy.SessionLifetimeHours),
+		DefaultUserType:       policy.DefaultUserType,
+	}
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Kotlang/authGo/token"
+)
+
+// tokenAuth replaces go-api-boot's shared secret verification with authGo's signing keys.
+// Embedded in every service requiring an authenticated user.
+type tokenAuth struct {
+	keys *token.KeyStore
+}
+
+func (a *tokenAuth) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	return a.keys.Authenticate(ctx)
+}
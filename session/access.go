@@ -0,0 +1,65 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kotlang/authGo/apierror"
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/token"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CheckAccess checks the server side state of a verified access token: its session is
+// active, the user exists, is neither blocked nor marked for deletion and the token
+// is from the user's current token epoch. Returns the user's login.
+func (s *Store) CheckAccess(ctx context.Context, tenant string, claims *token.Claims) (*db.LoginModel, error) {
+	userId := claims.ID
+
+	// tokens issued before sessions were introduced carry no session.
+	if claims.SessionId != "" {
+		revoked, err := s.IsRevoked(ctx, tenant, claims.SessionId)
+		if err != nil {
+			logger.Error("Failed checking session", zap.String("sessionId", claims.SessionId), zap.Error(err))
+			return nil, status.Error(codes.Unavailable, "Failed checking session")
+		}
+		if revoked {
+			return nil, apierror.New(codes.Unauthenticated, apierror.ReasonSessionRevoked, "Session is revoked. Login again", 0, nil)
+		}
+	}
+
+	login, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).FindOneByID(ctx, userId))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		logger.Error("User not found", zap.String("userId", userId))
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonUserNotFound, "User not found. Login again", 0, nil)
+	}
+	if err != nil {
+		logger.Error("Failed getting login", zap.String("userId", userId), zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "Failed checking user")
+	}
+
+	if login.IsBlockedAt(time.Now().Unix()) {
+		logger.Error("User is blocked", zap.String("userId", userId))
+		return nil, apierror.UserBlocked(login.BlockInfo.Reason, login.BlockInfo.ExpiresOn)
+	}
+
+	if login.IsDeleting() {
+		logger.Error("User is marked for deletion", zap.String("userId", userId))
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonUserMarkedForDeletion, "User is marked for deletion", 0, nil)
+	}
+
+	// token issued before the user was blocked, deleted or had user type changed.
+	if claims.TokenEpoch != login.TokenEpoch {
+		logger.Error("Stale token", zap.String("userId", userId), zap.Int64("tokenEpoch", claims.TokenEpoch), zap.Int64("userEpoch", login.TokenEpoch))
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonTokenStale, "Token is no longer valid. Refresh or login again", 0, nil)
+	}
+
+	return login, nil
+}
@@ -0,0 +1,256 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/ratelimit"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/metadata"
+)
+
+// revoke reasons.
+const (
+	RevokeReasonTokenReused = "refresh_token_reused"
+	RevokeReasonLogout      = "logout"
+	RevokeReasonUser        = "revoked_by_user"
+	RevokeReasonAdmin       = "revoked_by_admin"
+)
+
+// cached status is re-checked after this duration, so revocations on other
+// instances are picked up within it.
+const statusCacheTtl = 30 * time.Second
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// Store keeps sessions of a tenant and issues rotating refresh tokens for them.
+// Refresh tokens are "<sessionId>.<secret>" and only their hash is stored.
+// Revocation status of sessions is cached in process as it is checked on every call.
+type Store struct {
+	mongo odm.MongoClient
+	// tenant/sessionId -> cachedStatus
+	statusCache sync.Map
+	lastSweep   atomic.Int64
+}
+
+type cachedStatus struct {
+	revoked   bool
+	checkedAt time.Time
+}
+
+func ProvideStore(mongo odm.MongoClient) *Store {
+	return &Store{mongo: mongo}
+}
+
+// Create starts a new session for the user and returns it with its refresh token.
+// A session not refreshed within lifetime expires.
+func (s *Store) Create(ctx context.Context, tenant, userId string, device db.DeviceInfo, lifetime time.Duration) (*db.SessionModel, string, error) {
+	sessionId := uuid.New().String()
+	refreshToken, err := newRefreshToken(sessionId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := db.SessionModel{
+		SessionId:          sessionId,
+		UserId:             userId,
+		RefreshTokenHash:   hashToken(refreshToken),
+		RotatedTokenHashes: []string{},
+		Device:             device,
+		LastRefreshedOn:    now.Unix(),
+		ExpiresOn:          now.Add(lifetime).Unix(),
+	}
+
+	_, err = async.Await(odm.CollectionOf[db.SessionModel](s.mongo, tenant).Save(ctx, session))
+	if err != nil {
+		return nil, "", err
+	}
+	return &session, refreshToken, nil
+}
+
+// Refresh rotates the refresh token and returns the session with the new token.
+// Presenting an already rotated token revokes the session, as either the
+// client or an attacker is holding a stolen token.
+// The session is extended by lifetime from now.
+func (s *Store) Refresh(ctx context.Context, tenant, refreshToken string, lifetime time.Duration) (*db.SessionModel, string, error) {
+	sessionId, _, found := strings.Cut(refreshToken, ".")
+	if !found || sessionId == "" {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	newToken, err := newRefreshToken(sessionId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	presentedHash := hashToken(refreshToken)
+	session, err := db.RotateRefreshToken(ctx, s.mongo, tenant, sessionId, presentedHash, hashToken(newToken), now.Unix(), now.Add(lifetime).Unix())
+	if err == nil {
+		return session, newToken, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, "", err
+	}
+
+	// token is not the current one of an active session, check if it was rotated earlier.
+	session, err = async.Await(odm.CollectionOf[db.SessionModel](s.mongo, tenant).FindOneByID(ctx, sessionId))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, "", ErrInvalidRefreshToken
+		}
+		return nil, "", err
+	}
+
+	if !slices.Contains(session.RotatedTokenHashes, presentedHash) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	_, err = db.RevokeSessions(ctx, s.mongo, tenant, bson.M{"_id": sessionId}, RevokeReasonTokenReused, now.Unix())
+	if err != nil {
+		return nil, "", err
+	}
+	s.markRevoked(tenant, sessionId)
+	return session, "", ErrRefreshTokenReused
+}
+
+// IsRevoked returns true if the session is revoked or has expired.
+func (s *Store) IsRevoked(ctx context.Context, tenant, sessionId string) (bool, error) {
+	s.sweepCache()
+
+	key := tenant + "/" + sessionId
+	if cached, ok := s.statusCache.Load(key); ok {
+		status := cached.(cachedStatus)
+		if time.Since(status.checkedAt) < statusCacheTtl {
+			return status.revoked, nil
+		}
+	}
+
+	session, err := async.Await(odm.CollectionOf[db.SessionModel](s.mongo, tenant).FindOneByID(ctx, sessionId))
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return false, err
+	}
+
+	revoked := session == nil || session.RevokedOn != 0 || session.ExpiresOn < time.Now().Unix()
+	s.statusCache.Store(key, cachedStatus{revoked: revoked, checkedAt: time.Now()})
+	return revoked, nil
+}
+
+// ListActive returns sessions of the user which are neither revoked nor expired.
+func (s *Store) ListActive(ctx context.Context, tenant, userId string) ([]db.SessionModel, error) {
+	filter := bson.M{
+		"userId":    userId,
+		"revokedOn": 0,
+		"expiresOn": bson.M{"$gt": time.Now().Unix()},
+	}
+	return async.Await(odm.CollectionOf[db.SessionModel](s.mongo, tenant).Find(ctx, filter, bson.D{{Key: "lastRefreshedOn", Value: -1}}, 0, 0))
+}
+
+// Revoke revokes a session of the user. Returns false if there is no such active session.
+func (s *Store) Revoke(ctx context.Context, tenant, userId, sessionId, reason string) (bool, error) {
+	count, err := db.RevokeSessions(ctx, s.mongo, tenant, bson.M{"_id": sessionId, "userId": userId}, reason, time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+	s.markRevoked(tenant, sessionId)
+	return count > 0, nil
+}
+
+// RevokeAll revokes every active session of the user and returns the number revoked.
+func (s *Store) RevokeAll(ctx context.Context, tenant, userId, reason string) (int64, error) {
+	sessions, err := s.ListActive(ctx, tenant, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := db.RevokeSessions(ctx, s.mongo, tenant, bson.M{"userId": userId}, reason, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, userSession := range sessions {
+		s.markRevoked(tenant, userSession.SessionId)
+	}
+	return count, nil
+}
+
+// DeleteAll removes every session of the user, used when the account is purged.
+// Tokens of deleted sessions are rejected like those of revoked ones.
+func (s *Store) DeleteAll(ctx context.Context, tenant, userId string) (int64, error) {
+	sessions, err := s.ListActive(ctx, tenant, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := db.DeleteSessionsOfUser(ctx, s.mongo, tenant, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, userSession := range sessions {
+		s.markRevoked(tenant, userSession.SessionId)
+	}
+	return count, nil
+}
+
+func (s *Store) markRevoked(tenant, sessionId string) {
+	s.statusCache.Store(tenant+"/"+sessionId, cachedStatus{revoked: true, checkedAt: time.Now()})
+}
+
+// drops stale entries at most once a minute so that the cache does not grow with every session seen.
+func (s *Store) sweepCache() {
+	now := time.Now()
+	last := s.lastSweep.Load()
+	if now.Unix()-last < 60 || !s.lastSweep.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+
+	s.statusCache.Range(func(key, value any) bool {
+		if now.Sub(value.(cachedStatus).checkedAt) >= statusCacheTtl {
+			s.statusCache.Delete(key)
+		}
+		return true
+	})
+}
+
+// DeviceFromContext reads device details of the caller from grpc metadata.
+func DeviceFromContext(ctx context.Context) db.DeviceInfo {
+	device := db.DeviceInfo{Ip: ratelimit.ClientIp(ctx)}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if userAgent := md.Get("user-agent"); len(userAgent) > 0 {
+			device.UserAgent = userAgent[0]
+		}
+	}
+	return device
+}
+
+func newRefreshToken(sessionId string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return sessionId + "." + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,26 @@
+package superadmin
+
+import "context"
+
+// TargetTenantHeader names the tenant a super admin call operates on.
+const TargetTenantHeader = "x-target-tenant"
+
+// Call is a super admin's call against a tenant other than their own.
+type Call struct {
+	UserId string
+	// platform tenant the super admin logged into.
+	HomeTenant   string
+	TargetTenant string
+}
+
+type callContextKey struct{}
+
+func WithCall(ctx context.Context, call Call) context.Context {
+	return context.WithValue(ctx, callContextKey{}, call)
+}
+
+// CallFromContext returns the cross tenant call being served, false for regular calls.
+func CallFromContext(ctx context.Context) (Call, bool) {
+	call, ok := ctx.Value(callContextKey{}).(Call)
+	return call, ok
+}
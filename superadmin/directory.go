@@ -0,0 +1,100 @@
+package superadmin
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Kotlang/authGo/appconfig"
+	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// grants and revocations made on other instances are picked up within this duration.
+const directoryCacheTtl = 30 * time.Second
+
+type cachedGrant struct {
+	granted  bool
+	loadedAt time.Time
+}
+
+// Directory holds super admins in the control plane database.
+// Super admins are users of the platform tenant, they log in there like any other user.
+type Directory struct {
+	mongo          odm.MongoClient
+	database       string
+	platformTenant string
+	lock           sync.RWMutex
+	cache          map[string]cachedGrant
+}
+
+func ProvideDirectory(mongo odm.MongoClient, ccfg *appconfig.AppConfig) *Directory {
+	return &Directory{
+		mongo:          mongo,
+		database:       ccfg.ControlPlaneDatabase(),
+		platformTenant: ccfg.PlatformTenant,
+		cache:          map[string]cachedGrant{},
+	}
+}
+
+// IsSuperAdmin reports if the user of the tenant is a super admin.
+// Users of tenants other than the platform tenant never are.
+func (d *Directory) IsSuperAdmin(ctx context.Context, tenant, userId string) (bool, error) {
+	if d.platformTenant == "" || tenant != d.platformTenant || userId == "" {
+		return false, nil
+	}
+
+	d.lock.RLock()
+	cached, ok := d.cache[userId]
+	d.lock.RUnlock()
+	if ok && time.Since(cached.loadedAt) < directoryCacheTtl {
+		return cached.granted, nil
+	}
+
+	_, err := async.Await(odm.CollectionOf[db.SuperAdminModel](d.mongo, d.database).FindOneByID(ctx, userId))
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return false, err
+	}
+
+	granted := err == nil
+	d.lock.Lock()
+	d.cache[userId] = cachedGrant{granted: granted, loadedAt: time.Now()}
+	d.lock.Unlock()
+	return granted, nil
+}
+
+// Grant makes the platform tenant user a super admin.
+func (d *Directory) Grant(ctx context.Context, userId, note, grantedBy string) error {
+	_, err := async.Await(odm.CollectionOf[db.SuperAdminModel](d.mongo, d.database).Save(ctx, db.SuperAdminModel{
+		UserId:    userId,
+		Note:      note,
+		GrantedBy: grantedBy,
+	}))
+	d.invalidate(userId)
+	return err
+}
+
+// Revoke removes the grant. Returns false if the user was not a super admin.
+func (d *Directory) Revoke(ctx context.Context, userId string) (bool, error) {
+	res, err := d.mongo.Database(d.database).Collection(db.SuperAdminModel{}.CollectionName()).
+		DeleteOne(ctx, bson.M{"_id": userId})
+	d.invalidate(userId)
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
+
+func (d *Directory) List(ctx context.Context) ([]db.SuperAdminModel, error) {
+	return async.Await(odm.CollectionOf[db.SuperAdminModel](d.mongo, d.database).Find(ctx, bson.M{}, bson.D{{Key: "createdOn", Value: 1}}, 0, 0))
+}
+
+func (d *Directory) invalidate(userId string) {
+	d.lock.Lock()
+	delete(d.cache, userId)
+	d.lock.Unlock()
+}
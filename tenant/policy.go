@@ -0,0 +1,48 @@
+package tenant
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/rbac"
+)
+
+// ValidatePolicy checks the values set in a policy. Zero values are allowed and take defaults.
+func ValidatePolicy(policy db.TenantPolicy) error {
+	if policy.Registration != "" && policy.Registration != db.RegistrationOpen && policy.Registration != db.RegistrationInviteOnly {
+		return fmt.Errorf("registration should be %s or %s", db.RegistrationOpen, db.RegistrationInviteOnly)
+	}
+
+	for _, identifier := range policy.AllowedIdentifiers {
+		if identifier != db.IdentifierPhone && identifier != db.IdentifierEmail {
+			return fmt.Errorf("allowed identifiers should be %s or %s", db.IdentifierPhone, db.IdentifierEmail)
+		}
+	}
+
+	// every new user of the tenant would be an admin.
+	if strings.EqualFold(strings.TrimSpace(policy.DefaultUserType), rbac.AdminUserType) {
+		return fmt.Errorf("default user type can't be %s", rbac.AdminUserType)
+	}
+
+	if err := checkRange("otp length", policy.OtpLength, 4, 10); err != nil {
+		return err
+	}
+	if err := checkRange("otp ttl seconds", policy.OtpTtlSeconds, 60, 3600); err != nil {
+		return err
+	}
+	if err := checkRange("resend interval seconds", policy.ResendIntervalSeconds, 10, 3600); err != nil {
+		return err
+	}
+	if err := checkRange("daily otp cap", policy.DailyOtpCap, 1, 100); err != nil {
+		return err
+	}
+	return checkRange("session lifetime hours", policy.SessionLifetimeHours, 1, 365*24)
+}
+
+func checkRange(name string, value, min, max int) error {
+	if value != 0 && (value < min || value > max) {
+		return fmt.Errorf("%s should be between %d and %d", name, min, max)
+	}
+	return nil
+}
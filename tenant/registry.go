@@ -0,0 +1,188 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Kotlang/authGo/apierror"
+	"github.com/Kotlang/authGo/appconfig"
+	"github.com/Kotlang/authGo/db"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// status changes made on other instances are picked up within this duration.
+const registryCacheTtl = 30 * time.Second
+
+// tenant name is used as database name, so it is kept short and safe.
+var tenantNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,37}$`)
+
+// mongo's own databases, never used as tenants.
+var reservedNames = []string{"admin", "local", "config"}
+
+var ErrTenantExists = errors.New("tenant already exists")
+
+var ErrTenantNameReserved = errors.New("tenant name is reserved")
+
+type cachedTenant struct {
+	tenant   *db.TenantModel
+	loadedAt time.Time
+}
+
+// Registry holds tenants in the control plane database.
+// Requests for tenants not in the registry are rejected so that they don't create databases.
+type Registry struct {
+	mongo    odm.MongoClient
+	database string
+	lock     sync.RWMutex
+	cache    map[string]cachedTenant
+}
+
+func ProvideRegistry(mongo odm.MongoClient, ccfg *appconfig.AppConfig) *Registry {
+	return &Registry{
+		mongo:    mongo,
+		database: ccfg.ControlPlaneDatabase(),
+		cache:    map[string]cachedTenant{},
+	}
+}
+
+func IsValidName(name string) bool {
+	return tenantNamePattern.MatchString(name) && !slices.Contains(reservedNames, name)
+}
+
+// Get returns the tenant, nil if it is not registered.
+func (r *Registry) Get(ctx context.Context, name string) (*db.TenantModel, error) {
+	r.lock.RLock()
+	cached, ok := r.cache[name]
+	r.lock.RUnlock()
+	if ok && time.Since(cached.loadedAt) < registryCacheTtl {
+		return cached.tenant, nil
+	}
+
+	tenant, err := async.Await(odm.CollectionOf[db.TenantModel](r.mongo, r.database).FindOneByID(ctx, name))
+	// unknown names are not cached so that random domains cannot grow the cache.
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.lock.Lock()
+	r.cache[name] = cachedTenant{tenant: tenant, loadedAt: time.Now()}
+	r.lock.Unlock()
+	return tenant, nil
+}
+
+// CheckActive returns a grpc error unless the tenant is registered and active.
+func (r *Registry) CheckActive(ctx context.Context, name string) error {
+	if !IsValidName(name) {
+		return apierror.New(codes.NotFound, apierror.ReasonTenantUnknown, "Unknown domain", 0, nil)
+	}
+
+	tenant, err := r.Get(ctx, name)
+	if err != nil {
+		logger.Error("Failed getting tenant", zap.String("tenant", name), zap.Error(err))
+		return status.Error(codes.Unavailable, "Failed checking domain")
+	}
+
+	if tenant == nil || tenant.Status == db.TenantDeleted {
+		return apierror.New(codes.NotFound, apierror.ReasonTenantUnknown, "Unknown domain", 0, nil)
+	}
+	if tenant.Status != db.TenantActive {
+		return apierror.New(codes.FailedPrecondition, apierror.ReasonTenantSuspended, "Domain is suspended", 0, nil)
+	}
+	return nil
+}
+
+// Create registers a new active tenant.
+func (r *Registry) Create(ctx context.Context, tenant db.TenantModel) (*db.TenantModel, error) {
+	// tenant data would be mixed with the registry.
+	if slices.Contains(reservedNames, tenant.Name) || tenant.Name == r.database {
+		return nil, ErrTenantNameReserved
+	}
+
+	tenant.Status = db.TenantActive
+	tenant.CreatedOn = time.Now().Unix()
+	if tenant.Settings == nil {
+		tenant.Settings = map[string]string{}
+	}
+
+	_, err := r.mongo.Database(r.database).Collection(tenant.CollectionName()).InsertOne(ctx, tenant)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrTenantExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(tenant.Name)
+	return r.Get(ctx, tenant.Name)
+}
+
+// List returns tenants which are not deleted.
+func (r *Registry) List(ctx context.Context) ([]db.TenantModel, error) {
+	return async.Await(odm.CollectionOf[db.TenantModel](r.mongo, r.database).Find(ctx,
+		bson.M{"status": bson.M{"$ne": db.TenantDeleted}},
+		bson.D{{Key: "_id", Value: 1}}, 0, 0))
+}
+
+// SetStatus updates status of a registered tenant. Returns nil if tenant is not registered.
+func (r *Registry) SetStatus(ctx context.Context, name, tenantStatus string) (*db.TenantModel, error) {
+	res, err := r.mongo.Database(r.database).Collection(db.TenantModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": name}, bson.M{"$set": bson.M{"status": tenantStatus}})
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(name)
+	if res.MatchedCount == 0 {
+		return nil, nil
+	}
+	return r.Get(ctx, name)
+}
+
+func (r *Registry) invalidate(name string) {
+	r.lock.Lock()
+	delete(r.cache, name)
+	r.lock.Unlock()
+}
+
+// Policy returns the tenant's authentication policy with defaults applied.
+// Errors reading the registry are returned instead of falling back to the defaults,
+// which may be less strict than the tenant's policy.
+func (r *Registry) Policy(ctx context.Context, name string) (db.TenantPolicy, error) {
+	tenant, err := r.Get(ctx, name)
+	if err != nil {
+		return db.TenantPolicy{}, err
+	}
+	if tenant == nil {
+		return db.TenantPolicy{}.WithDefaults(), nil
+	}
+	return tenant.Policy.WithDefaults(), nil
+}
+
+// SetPolicy replaces the tenant's policy. Returns nil if tenant is not registered.
+func (r *Registry) SetPolicy(ctx context.Context, name string, policy db.TenantPolicy) (*db.TenantModel, error) {
+	res, err := r.mongo.Database(r.database).Collection(db.TenantModel{}.CollectionName()).
+		UpdateOne(ctx, bson.M{"_id": name}, bson.M{"$set": bson.M{"policy": policy}})
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(name)
+	if res.MatchedCount == 0 {
+		return nil, nil
+	}
+	return r.Get(ctx, name)
+}
@@ -0,0 +1,89 @@
+package token
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"go.uber.org/zap"
+)
+
+// JwksPath is where the public keys are published on the http port.
+// Keys are per tenant: /.well-known/jwks.json?tenant=<tenant>
+const JwksPath = "/.well-known/jwks.json"
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JwkSet struct {
+	Keys []Jwk `json:"keys"`
+}
+
+// Jwks returns public keys of the tenant's current key and keys in grace period.
+func (k *KeyStore) Jwks(ctx context.Context, tenant string) (*JwkSet, error) {
+	keys, err := k.getTenantKeys(ctx, tenant, keyReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	set := &JwkSet{Keys: []Jwk{}}
+	for _, key := range keys.keys {
+		if !key.isValid(now) {
+			continue
+		}
+
+		public := key.private.PublicKey
+		set.Keys = append(set.Keys, Jwk{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: signingAlgorithm,
+			Kid: key.kid,
+			N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		})
+	}
+
+	// stable order so that responses can be cached.
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set, nil
+}
+
+// JwksHandler serves the JWKS document of a tenant for services verifying access tokens.
+func (k *KeyStore) JwksHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.URL.Query().Get("tenant")
+		if tenant == "" {
+			http.Error(w, "tenant is required", http.StatusBadRequest)
+			return
+		}
+
+		set, err := k.Jwks(r.Context(), tenant)
+		if errors.Is(err, ErrUnknownTenant) {
+			http.Error(w, "unknown tenant", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.Error("Failed getting jwks", zap.Error(err))
+			http.Error(w, "Failed getting keys", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		// short enough for verifiers to see a rotated key well within the grace period.
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(set)
+	}
+}
@@ -0,0 +1,59 @@
+package token
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+)
+
+// masterKeyEnv holds base64 encoded 32 byte key encrypting signing keys at rest.
+const masterKeyEnv = "SIGNING-KEY-MASTER-KEY"
+
+func loadMasterKey() (cipher.AEAD, error) {
+	encoded := os.Getenv(masterKeyEnv)
+	if encoded == "" {
+		return nil, errors.New(masterKeyEnv + " is not set in environment")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New(masterKeyEnv + " is not valid base64")
+	}
+	if len(key) != 32 {
+		return nil, errors.New(masterKeyEnv + " should be 32 bytes")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypts with AES-GCM. Additional data binds the ciphertext to its tenant and kid
+// so that an encrypted key copied to another tenant does not decrypt.
+func encryptKey(aead cipher.AEAD, plaintext []byte, additionalData string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(additionalData))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptKey(aead cipher.AEAD, encrypted, additionalData string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted key is too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(additionalData))
+}
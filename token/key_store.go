@@ -0,0 +1,352 @@
+package token
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Kotlang/authGo/db"
+	"github.com/Kotlang/authGo/tenant"
+	"github.com/SaiNageswarS/go-api-boot/async"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+const (
+	signingAlgorithm = "RS256"
+	rsaKeyBits       = 2048
+	// retired keys keep verifying tokens for this long after rotation.
+	KeyGracePeriod = 24 * time.Hour
+	// keys rotated by other instances are picked up within this duration.
+	keyReloadInterval = time.Minute
+	// tokens with an unknown kid reload the tenant's keys at most this often.
+	unknownKidReloadInterval = 10 * time.Second
+	// unknown kids remembered per tenant, a remembered kid does not reload keys again.
+	maxUnknownKids = 1000
+)
+
+type signingKey struct {
+	kid       string
+	private   *rsa.PrivateKey
+	retiredOn int64
+}
+
+type tenantKeys struct {
+	keys map[string]*signingKey
+	// nil until the tenant's first token is signed.
+	current  *signingKey
+	loadedAt time.Time
+	// kids not found after a reload and when they were looked up.
+	unknownKids map[string]time.Time
+}
+
+// KeyStore signs access tokens of a tenant with the tenant's current key and verifies
+// them with any of the tenant's keys still in grace period. Keys are kept in the
+// tenant's database encrypted with the master key.
+type KeyStore struct {
+	mongo     odm.MongoClient
+	masterKey cipher.AEAD
+	registry  *tenant.Registry
+
+	lock    sync.RWMutex
+	tenants map[string]*tenantKeys
+}
+
+// ErrUnknownTenant is returned for tenants which are not in the registry.
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+func ProvideKeyStore(mongo odm.MongoClient, registry *tenant.Registry) (*KeyStore, error) {
+	masterKey, err := loadMasterKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyStore{
+		mongo:     mongo,
+		masterKey: masterKey,
+		registry:  registry,
+		tenants:   map[string]*tenantKeys{},
+	}, nil
+}
+
+// Sign signs the claims with the tenant's current key and sets its kid header.
+func (k *KeyStore) Sign(ctx context.Context, tenant string, claims jwt.Claims) (string, error) {
+	keys, err := k.getTenantKeys(ctx, tenant, keyReloadInterval)
+	if err != nil {
+		return "", err
+	}
+
+	// tenant's first token.
+	if keys.current == nil {
+		if err := k.createInitialKey(ctx, tenant); err != nil {
+			return "", err
+		}
+		if keys, err = k.getTenantKeys(ctx, tenant, 0); err != nil {
+			return "", err
+		}
+		if keys.current == nil {
+			return "", errors.New("no signing key for tenant " + tenant)
+		}
+	}
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	jwtToken.Header["kid"] = keys.current.kid
+	return jwtToken.SignedString(keys.current.private)
+}
+
+// Parse verifies the token with a key of the tenant in its audience claim and fills claims.
+// Key of one tenant never verifies a token claiming another tenant.
+func (k *KeyStore) Parse(ctx context.Context, tokenString string, claims *Claims) error {
+	parsed, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != signingAlgorithm {
+			return nil, errors.New("unexpected signing method " + t.Method.Alg())
+		}
+
+		tokenClaims, ok := t.Claims.(*Claims)
+		if !ok || tokenClaims.Tenant() == "" {
+			return nil, errors.New("token has no tenant")
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		key, err := k.getVerificationKey(ctx, tokenClaims.Tenant(), kid)
+		if err != nil {
+			return nil, err
+		}
+		return &key.private.PublicKey, nil
+	}, jwt.WithValidMethods([]string{signingAlgorithm}), jwt.WithExpirationRequired())
+	if err != nil {
+		return err
+	}
+	if !parsed.Valid {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+// Rotate makes a new key current for the tenant and retires the others.
+// Tokens signed with retired keys stay valid for KeyGracePeriod.
+func (k *KeyStore) Rotate(ctx context.Context, tenant string) (string, error) {
+	// keys are never written to databases of unknown tenants.
+	if err := k.checkRegistered(ctx, tenant); err != nil {
+		return "", err
+	}
+
+	newKey, err := k.newKeyModel(tenant, uuid.New().String())
+	if err != nil {
+		return "", err
+	}
+	if _, err := async.Await(odm.CollectionOf[db.SigningKeyModel](k.mongo, tenant).Save(ctx, newKey)); err != nil {
+		return "", err
+	}
+
+	_, err = k.mongo.Database(tenant).Collection(db.SigningKeyModel{}.CollectionName()).UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$ne": newKey.Kid}, "retiredOn": 0},
+		bson.M{"$set": bson.M{"retiredOn": time.Now().Unix()}})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = k.getTenantKeys(ctx, tenant, 0)
+	return newKey.Kid, err
+}
+
+// returns key with the kid if it belongs to the tenant and is current or in grace period.
+// An unknown kid reloads keys as it might have been created by another instance. The reload
+// is throttled and a kid still unknown after it does not reload keys again for keyReloadInterval.
+func (k *KeyStore) getVerificationKey(ctx context.Context, tenant, kid string) (*signingKey, error) {
+	keys, err := k.getTenantKeys(ctx, tenant, keyReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := keys.keys[kid]
+	if !ok && !k.isUnknownKid(keys, kid) {
+		keys, err = k.getTenantKeys(ctx, tenant, unknownKidReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok = keys.keys[kid]; !ok {
+			k.rememberUnknownKid(keys, kid)
+		}
+	}
+
+	if !ok || !key.isValid(time.Now()) {
+		return nil, errors.New("unknown or expired signing key " + kid)
+	}
+	return key, nil
+}
+
+func (k *KeyStore) isUnknownKid(keys *tenantKeys, kid string) bool {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	lookedUpOn, ok := keys.unknownKids[kid]
+	return ok && time.Since(lookedUpOn) < keyReloadInterval
+}
+
+func (k *KeyStore) rememberUnknownKid(keys *tenantKeys, kid string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if len(keys.unknownKids) < maxUnknownKids {
+		keys.unknownKids[kid] = time.Now()
+	}
+}
+
+// getTenantKeys returns the tenant's keys, reloading them when loaded more than maxAge ago.
+// A zero maxAge always reloads.
+func (k *KeyStore) getTenantKeys(ctx context.Context, tenant string, maxAge time.Duration) (*tenantKeys, error) {
+	k.lock.RLock()
+	cached, ok := k.tenants[tenant]
+	k.lock.RUnlock()
+	if ok && time.Since(cached.loadedAt) < maxAge {
+		return cached, nil
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	// reloaded by a concurrent call while waiting for the lock.
+	if cached, ok = k.tenants[tenant]; ok && time.Since(cached.loadedAt) < maxAge {
+		return cached, nil
+	}
+
+	// tenant comes from unverified tokens and requests, so only registered tenants get an entry.
+	if !ok {
+		if err := k.checkRegistered(ctx, tenant); err != nil {
+			return nil, err
+		}
+	}
+
+	models, err := async.Await(odm.CollectionOf[db.SigningKeyModel](k.mongo, tenant).Find(ctx,
+		bson.M{"$or": bson.A{
+			bson.M{"retiredOn": 0},
+			bson.M{"retiredOn": bson.M{"$gt": time.Now().Add(-KeyGracePeriod).Unix()}},
+		}},
+		bson.D{{Key: "activatedOn", Value: -1}}, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := &tenantKeys{keys: map[string]*signingKey{}, unknownKids: map[string]time.Time{}}
+	for _, model := range models {
+		key, err := k.decryptSigningKey(tenant, model)
+		if err != nil {
+			logger.Error("Failed decrypting signing key", zap.String("tenant", tenant), zap.String("kid", model.Kid), zap.Error(err))
+			continue
+		}
+		loaded.keys[key.kid] = key
+		// sorted by activation, newest non retired key signs.
+		if loaded.current == nil && key.retiredOn == 0 {
+			loaded.current = key
+		}
+	}
+
+	if ok {
+		for kid, lookedUpOn := range cached.unknownKids {
+			if _, found := loaded.keys[kid]; !found && time.Since(lookedUpOn) < keyReloadInterval {
+				loaded.unknownKids[kid] = lookedUpOn
+			}
+		}
+	}
+
+	loaded.loadedAt = time.Now()
+	k.tenants[tenant] = loaded
+	return loaded, nil
+}
+
+func (k *KeyStore) checkRegistered(ctx context.Context, name string) error {
+	if !tenant.IsValidName(name) {
+		return ErrUnknownTenant
+	}
+
+	registered, err := k.registry.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if registered == nil || registered.Status == db.TenantDeleted {
+		return ErrUnknownTenant
+	}
+	return nil
+}
+
+// createInitialKey creates the tenant's first key unless another instance already did.
+// All instances derive the same kid, so the conditional upsert lets only one of them insert it.
+func (k *KeyStore) createInitialKey(ctx context.Context, tenant string) error {
+	kid := uuid.NewSHA1(uuid.NameSpaceURL, []byte("authgo:signing-key:"+tenant+":initial")).String()
+	initialKey, err := k.newKeyModel(tenant, kid)
+	if err != nil {
+		return err
+	}
+
+	res, err := k.mongo.Database(tenant).Collection(db.SigningKeyModel{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": kid},
+		bson.M{"$setOnInsert": initialKey},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	if res.UpsertedCount == 1 {
+		logger.Info("Created first signing key", zap.String("tenant", tenant), zap.String("kid", kid))
+	}
+	return nil
+}
+
+// newKeyModel generates a key pair and returns it encrypted for the tenant.
+func (k *KeyStore) newKeyModel(tenant, kid string) (db.SigningKeyModel, error) {
+	private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	if err != nil {
+		return db.SigningKeyModel{}, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return db.SigningKeyModel{}, err
+	}
+
+	encrypted, err := encryptKey(k.masterKey, der, tenant+"/"+kid)
+	if err != nil {
+		return db.SigningKeyModel{}, err
+	}
+
+	now := time.Now().Unix()
+	return db.SigningKeyModel{
+		Kid:                 kid,
+		Algorithm:           signingAlgorithm,
+		EncryptedPrivateKey: encrypted,
+		ActivatedOn:         now,
+		CreatedOn:           now,
+	}, nil
+}
+
+func (k *KeyStore) decryptSigningKey(tenant string, model db.SigningKeyModel) (*signingKey, error) {
+	der, err := decryptKey(k.masterKey, model.EncryptedPrivateKey, tenant+"/"+model.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an rsa key")
+	}
+	return &signingKey{kid: model.Kid, private: private, retiredOn: model.RetiredOn}, nil
+}
+
+func (s *signingKey) isValid(now time.Time) bool {
+	return s.retiredOn == 0 || now.Before(time.Unix(s.retiredOn, 0).Add(KeyGracePeriod))
+}